@@ -0,0 +1,319 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// defaultBook is the phonebook stored in projects.json. Other books live in
+// books/<name>.json next to it.
+const defaultBook = "default"
+
+// allBooksLabel is shown in place of a book name in the merged view.
+const allBooksLabel = "all books"
+
+// selectedBook is the book named with --book; it wins over PHONEBOOK_BOOK.
+var selectedBook string
+
+var bookNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// validateBookName rejects names that can't be used as file names.
+func validateBookName(name string) error {
+	if !bookNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid book name %q (use letters, digits, - and _)", name)
+	}
+	return nil
+}
+
+// activeBook returns the book chosen by --book, PHONEBOOK_BOOK or the
+// default_book setting.
+func activeBook() string {
+	if selectedBook != "" {
+		return selectedBook
+	}
+	// takeBookFlag refuses an invalid PHONEBOOK_BOOK; it must never point
+	// the store outside the books directory either way.
+	if env, err := envBook(); err == nil && env != "" {
+		return env
+	}
+	if userConfig.DefaultBook != "" {
+		return userConfig.DefaultBook
+	}
+	return defaultBook
+}
+
+// envBook returns the book named by PHONEBOOK_BOOK, or "" if it isn't set.
+func envBook() (string, error) {
+	env := os.Getenv("PHONEBOOK_BOOK")
+	if env == "" {
+		return "", nil
+	}
+	if err := validateBookName(env); err != nil {
+		return "", fmt.Errorf("PHONEBOOK_BOOK: %w", err)
+	}
+	return env, nil
+}
+
+// takeBookFlag removes leading --book <name> (or --book=<name>) arguments,
+// which apply to the TUI and every subcommand alike, and records the book.
+// Without --book, PHONEBOOK_BOOK is checked instead.
+func takeBookFlag(args []string) ([]string, error) {
+flags:
+	for len(args) > 0 {
+		arg := args[0]
+		switch {
+		case arg == "--book" || arg == "-book":
+			if len(args) < 2 {
+				return nil, fmt.Errorf("--book needs a name")
+			}
+			selectedBook, args = args[1], args[2:]
+		case strings.HasPrefix(arg, "--book="):
+			selectedBook, args = strings.TrimPrefix(arg, "--book="), args[1:]
+		default:
+			break flags
+		}
+		if err := validateBookName(selectedBook); err != nil {
+			return nil, err
+		}
+	}
+	if selectedBook == "" {
+		if _, err := envBook(); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+// storeDir is the directory holding projects.json and the other books:
+// store_dir if configured, otherwise $XDG_DATA_HOME/phonebook. Stores created
+// by older versions in ~/.config/projects keep being used until moved.
+func storeDir() string {
+	if userConfig.StoreDir != "" {
+		return expandPath(userConfig.StoreDir)
+	}
+	dir := xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+	if _, err := os.Stat(filepath.Join(dir, "projects.json")); os.IsNotExist(err) {
+		legacy := legacyDir()
+		if _, err := os.Stat(filepath.Join(legacy, "projects.json")); err == nil {
+			return legacy
+		}
+	}
+	return dir
+}
+
+// bookFile returns the store for the named book.
+func bookFile(name string) string {
+	if name == defaultBook {
+		return filepath.Join(storeDir(), "projects.json")
+	}
+	return filepath.Join(storeDir(), "books", name+".json")
+}
+
+// listBooks returns the default book followed by every other book on disk.
+func listBooks() []string {
+	books := []string{defaultBook}
+	matches, _ := filepath.Glob(filepath.Join(storeDir(), "books", "*.json"))
+	var named []string
+	for _, f := range matches {
+		name := strings.TrimSuffix(filepath.Base(f), ".json")
+		if validateBookName(name) == nil && name != defaultBook {
+			named = append(named, name)
+		}
+	}
+	sort.Strings(named)
+	return append(books, named...)
+}
+
+// setBook points the model at another book and loads it. Undo history
+// belongs to the book it was recorded in, so it is dropped.
+func (m *model) setBook(name string) error {
+	if err := validateBookName(name); err != nil {
+		return err
+	}
+	m.book = name
+	m.allBooks = false
+	m.projectsFile = bookFile(name)
+	os.MkdirAll(filepath.Dir(m.projectsFile), 0o755)
+	m.undoStack, m.redoStack = nil, nil
+	return m.loadProjects()
+}
+
+// showAllBooks switches to the read-only view of every book at once.
+func (m *model) showAllBooks() error {
+	m.allBooks = true
+	m.undoStack, m.redoStack = nil, nil
+	return m.loadProjects()
+}
+
+// loadAllBooks loads every book into one list, remembering where each
+// project came from. Unreadable books are skipped with an error afterwards.
+func (m *model) loadAllBooks() error {
+	m.storeErr = nil
+	m.projects = []Project{}
+	var failed []string
+	for _, name := range listBooks() {
+		data, err := os.ReadFile(bookFile(name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		var ps []Project
+		if err == nil {
+			ps, err = parseProjects(data)
+		}
+		if err != nil {
+			failed = append(failed, name)
+			continue
+		}
+		for _, p := range ps {
+			p.Book = name
+			m.projects = append(m.projects, p)
+		}
+	}
+	m.base = cloneProjects(m.projects)
+	m.disk = fileState{}
+	m.checkHealth()
+	if len(failed) > 0 {
+		return fmt.Errorf("could not read %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// bookLabel names what the model is showing.
+func (m *model) bookLabel() string {
+	if m.allBooks {
+		return allBooksLabel
+	}
+	return m.book
+}
+
+// editKeys are the list-view bindings that change the store; they are
+// refused in the all-books view.
+func editKeys() []key.Binding {
+	l := keymap.List
+	return []key.Binding{
+		l.Add, l.Edit, l.Delete, l.Undo, l.Redo,
+		l.Pin, l.PinUp, l.PinDown, l.Scan, l.Trash, l.Relocate, l.Merge,
+	}
+}
+
+// errReadOnly is returned for changes attempted in the all-books view.
+func errReadOnly() error {
+	return fmt.Errorf("the all-books view is read-only; press %s to switch to a book", keyHint(keymap.List.Books))
+}
+
+// recordOpenInBook records an open of the project with key in the named
+// book, for opens made from the all-books view.
+func recordOpenInBook(book, key string, t time.Time) error {
+	b := newModel()
+	if err := b.setBook(book); err != nil {
+		return err
+	}
+	for i, p := range b.projects {
+		if projectKey(p) == key {
+			b.projects[i].recordOpen(t)
+			return b.saveProjects()
+		}
+	}
+	return nil
+}
+
+// openBookPicker shows the list of books.
+func (m *model) openBookPicker() {
+	m.mode = viewBooks
+	m.bookNames = listBooks()
+	m.bookCursor = 0
+	for i, name := range m.bookNames {
+		if !m.allBooks && name == m.book {
+			m.bookCursor = i
+		}
+	}
+	if m.allBooks {
+		m.bookCursor = len(m.bookNames)
+	}
+	m.statusMessage = ""
+}
+
+// updateBooks handles keys in the book picker. The entry after the last
+// book is the all-books view.
+func (m model) updateBooks(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	bk := keymap.Books
+	entries := len(m.bookNames) + 1
+	switch {
+	case msg.String() == quitKey:
+		return m, tea.Quit
+	case key.Matches(msg, bk.Close) || reopens(msg, keymap.List.Books, "books_view"):
+		m.mode = viewList
+		return m, nil
+	case key.Matches(msg, bk.Down):
+		m.bookCursor = (m.bookCursor + 1) % entries
+	case key.Matches(msg, bk.Up):
+		m.bookCursor = (m.bookCursor - 1 + entries) % entries
+	case key.Matches(msg, bk.Switch):
+		var err error
+		if m.bookCursor == len(m.bookNames) {
+			err = m.showAllBooks()
+		} else {
+			err = m.setBook(m.bookNames[m.bookCursor])
+		}
+		m.mode = viewList
+		m.textInput.SetValue("")
+		m.filterQuery = ""
+		m.applyFilter("")
+		if err != nil {
+			m.statusMessage = fmt.Sprintf("Error: %v", err)
+			m.isError = true
+			return m, nil
+		}
+		m.statusMessage = fmt.Sprintf("✓ Switched to %s", m.bookLabel())
+		m.isError = false
+	}
+	return m, nil
+}
+
+// booksView renders the book picker.
+func (m model) booksView() string {
+	var b strings.Builder
+
+	header := lipgloss.NewStyle().
+		Foreground(primaryColor).
+		Bold(true).
+		Background(bgColor).
+		Padding(1, 2).
+		MarginBottom(1).
+		Width(70).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(primaryColor).
+		Render("📚 Phonebooks")
+	b.WriteString(header + "\n")
+
+	muted := lipgloss.NewStyle().Foreground(mutedColor)
+	for i, name := range append(append([]string{}, m.bookNames...), allBooksLabel) {
+		line := name
+		if i < len(m.bookNames) {
+			if !m.allBooks && name == m.book {
+				line += " (current)"
+			}
+		} else {
+			line += muted.Render("  read-only")
+		}
+		if i == m.bookCursor {
+			b.WriteString(selectedItemStyle.Render("▶ "+line) + "\n")
+		} else {
+			b.WriteString(normalItemStyle.Render(line) + "\n")
+		}
+	}
+
+	b.WriteString("\n" + muted.Italic(true).Render("New books are created with: phonebook --book <name> add …") + "\n")
+	b.WriteString(m.helpBar(keymap.Books.shortHelp(), ""))
+
+	return lipgloss.NewStyle().Padding(1, 2).Render(b.String())
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// withHome points the store at a new temporary home directory.
+func withHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("PHONEBOOK_BOOK", "")
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	selectedBook, userConfig = "", Config{}
+	t.Cleanup(func() { selectedBook, userConfig = "", Config{} })
+	return home
+}
+
+func TestValidateBookName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"work", false},
+		{"side-projects_2", false},
+		{"", true},
+		{"-work", true},
+		{"../work", true},
+		{"a/b", true},
+		{"work.json", true},
+		{"my book", true},
+	}
+	for _, tt := range tests {
+		if err := validateBookName(tt.name); (err != nil) != tt.wantErr {
+			t.Errorf("validateBookName(%q) error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestTakeBookFlag(t *testing.T) {
+	tests := []struct {
+		args     []string
+		wantArgs []string
+		wantBook string
+		wantErr  bool
+	}{
+		{[]string{"list"}, []string{"list"}, "", false},
+		{[]string{"--book", "work", "list"}, []string{"list"}, "work", false},
+		{[]string{"--book=work"}, []string{}, "work", false},
+		{[]string{"-book", "a", "--book", "b", "add"}, []string{"add"}, "b", false},
+		{[]string{"list", "--book", "work"}, []string{"list", "--book", "work"}, "", false},
+		{[]string{"--book"}, nil, "", true},
+		{[]string{"--book", "../x"}, nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.args), func(t *testing.T) {
+			withHome(t)
+			args, err := takeBookFlag(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if fmt.Sprint(args) != fmt.Sprint(tt.wantArgs) || selectedBook != tt.wantBook {
+				t.Errorf("got %v and book %q, want %v and book %q", args, selectedBook, tt.wantArgs, tt.wantBook)
+			}
+		})
+	}
+}
+
+func TestActiveBook(t *testing.T) {
+	withHome(t)
+	if got := activeBook(); got != defaultBook {
+		t.Errorf("activeBook() = %q, want %q", got, defaultBook)
+	}
+	t.Setenv("PHONEBOOK_BOOK", "env")
+	if got := activeBook(); got != "env" {
+		t.Errorf("activeBook() = %q with PHONEBOOK_BOOK set, want env", got)
+	}
+	userConfig.DefaultBook = "config"
+	if got := activeBook(); got != "env" {
+		t.Errorf("activeBook() = %q with PHONEBOOK_BOOK and default_book set, want env", got)
+	}
+	t.Setenv("PHONEBOOK_BOOK", "")
+	if got := activeBook(); got != "config" {
+		t.Errorf("activeBook() = %q with default_book set, want config", got)
+	}
+	selectedBook = "flag"
+	if got := activeBook(); got != "flag" {
+		t.Errorf("activeBook() = %q with --book set, want flag", got)
+	}
+}
+
+func TestInvalidBookEnv(t *testing.T) {
+	home := withHome(t)
+	t.Setenv("PHONEBOOK_BOOK", "../../../escaped/x")
+	if _, err := takeBookFlag([]string{"list"}); err == nil {
+		t.Error("takeBookFlag(list) accepted an invalid PHONEBOOK_BOOK")
+	}
+	if _, err := takeBookFlag([]string{"--book", "work"}); err != nil {
+		t.Errorf("takeBookFlag(--book work) = %v; --book should override PHONEBOOK_BOOK", err)
+	}
+	selectedBook = ""
+	if got := activeBook(); got != defaultBook {
+		t.Errorf("activeBook() = %q with an invalid PHONEBOOK_BOOK, want %q", got, defaultBook)
+	}
+	newModel()
+	if _, err := os.Stat(filepath.Join(home, ".local", "escaped")); !os.IsNotExist(err) {
+		t.Errorf("newModel created a directory outside the store: %v", err)
+	}
+}
+
+func TestBookFile(t *testing.T) {
+	home := withHome(t)
+	dir := filepath.Join(home, ".local", "share", "phonebook")
+	if got, want := bookFile(defaultBook), filepath.Join(dir, "projects.json"); got != want {
+		t.Errorf("bookFile(default) = %q, want %q", got, want)
+	}
+	if got, want := bookFile("work"), filepath.Join(dir, "books", "work.json"); got != want {
+		t.Errorf("bookFile(work) = %q, want %q", got, want)
+	}
+}
+
+func TestBooks(t *testing.T) {
+	withHome(t)
+	m := newModel()
+	if err := m.load(); err != nil {
+		t.Fatal(err)
+	}
+	m.projects = []Project{testProject("api", t0)}
+	if err := m.saveProjects(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.setBook("work"); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.projects) != 0 {
+		t.Fatalf("new book holds %d projects, want none", len(m.projects))
+	}
+	m.projects = []Project{testProject("blog", t1)}
+	if err := m.saveProjects(); err != nil {
+		t.Fatal(err)
+	}
+	// A stray file that isn't a valid book name is not listed.
+	os.WriteFile(filepath.Join(storeDir(), "books", "not a book.json"), []byte("[]"), 0o644)
+
+	if got, want := fmt.Sprint(listBooks()), "[default work]"; got != want {
+		t.Errorf("listBooks() = %s, want %s", got, want)
+	}
+	if err := m.setBook("../x"); err == nil {
+		t.Error("setBook accepted a name outside the books directory")
+	}
+
+	if err := m.showAllBooks(); err != nil {
+		t.Fatal(err)
+	}
+	books := make(map[string]string)
+	for _, p := range m.projects {
+		books[p.Name] = p.Book
+	}
+	if got, want := fmt.Sprint(books), "map[api:default blog:work]"; got != want {
+		t.Errorf("all books hold %s, want %s", got, want)
+	}
+	if err := m.saveProjects(); err == nil || err.Error() != errReadOnly().Error() {
+		t.Errorf("saveProjects() in the all-books view = %v, want %v", err, errReadOnly())
+	}
+
+	// Opens from the all-books view are recorded in the project's own book.
+	if err := m.markOpened(indexOfName(t, &m, "blog")); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.setBook("work"); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.projects[0].OpenCount; got != 1 {
+		t.Errorf("open count in the work book = %d, want 1", got)
+	}
+}
@@ -0,0 +1,1061 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/term"
+)
+
+// Exit codes used by the subcommands.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+const cliUsage = `Usage: phonebook [command] [flags]
+
+Run without a command to start the interactive picker.
+
+Flags:
+  --book <name>       Use the named phonebook (also PHONEBOOK_BOOK); must come
+                      before the command
+  --print-path        Print the path chosen with Enter instead of opening it
+  --out <file>        Write the chosen path to <file> (implies --print-path)
+
+Commands:
+  add <name> <path>   Add a project (--tag, --desc, --opener)
+  list                List all projects (--all-books to include every book)
+  books               List phonebooks and how many projects each holds
+  rm <name>           Move a project to the trash (--purge to delete it)
+  trash [list]        List projects in the trash (--json)
+  trash restore <name>
+                      Take a project out of the trash
+  trash purge [name]  Permanently delete one or all trashed projects
+  search <query>      Fuzzy search projects
+  path <name>         Print the path of a project
+  open <name>         Open a project with its configured opener
+  init <shell>        Print shell integration for bash, zsh or fish (--cmd)
+  restore [backup]    Restore projects.json from the newest valid backup
+  scan [roots...]     Find unregistered projects (--depth, --import)
+  retag [names...]    Re-detect languages and frameworks (--replace, --dry-run)
+  import <source> [file]
+                      Import from vscode, projectile, zoxide, tmuxinator,
+                      smug, a list of paths or a csv, yaml, toml or json
+                      export (--yes, --select, --json)
+  export [query]      Write projects as csv, yaml, toml, markdown or json
+                      (--format, --out)
+  doctor              Report missing, duplicate and nested projects (--fix)
+  merge <keep> <name>...
+                      Fold other entries' tags and history into <keep>
+  sync                Commit, pull, merge and push the phonebooks
+  sync init [remote]  Keep the phonebooks in a git repository synced with
+                      remote; every save is committed
+  config              Show where the config and projects live and check the
+                      config for problems
+  help                Show this help
+
+list, search, add, path and scan accept --json for machine-readable output.
+`
+
+// errUsage marks errors caused by bad arguments rather than failed operations.
+var errUsage = errors.New("usage")
+
+// runCLI runs a subcommand and returns the process exit code.
+func runCLI(args []string) int {
+	var err error
+	switch args[0] {
+	case "add":
+		err = cmdAdd(args[1:])
+	case "list", "ls":
+		err = cmdList(args[1:])
+	case "books":
+		err = cmdBooks(args[1:])
+	case "rm", "remove":
+		err = cmdRemove(args[1:])
+	case "search":
+		err = cmdSearch(args[1:])
+	case "path":
+		err = cmdPath(args[1:])
+	case "open":
+		err = cmdOpen(args[1:])
+	case "init":
+		err = cmdInit(args[1:])
+	case "restore":
+		err = cmdRestore(args[1:])
+	case "scan":
+		err = cmdScan(args[1:])
+	case "retag":
+		err = cmdRetag(args[1:])
+	case "trash":
+		err = cmdTrash(args[1:])
+	case "doctor":
+		err = cmdDoctor(args[1:])
+	case "merge":
+		err = cmdMerge(args[1:])
+	case "import":
+		err = cmdImport(args[1:])
+	case "export":
+		err = cmdExport(args[1:])
+	case "sync":
+		err = cmdSync(args[1:])
+	case "config":
+		err = cmdConfig(args[1:])
+	case "help", "-h", "--help":
+		fmt.Fprint(os.Stdout, cliUsage)
+		return exitOK
+	default:
+		fmt.Fprintf(os.Stderr, "phonebook: unknown command %q\n\n%s", args[0], cliUsage)
+		return exitUsage
+	}
+
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(os.Stderr, "phonebook: %v\n", err)
+		return exitUsage
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	default:
+		fmt.Fprintf(os.Stderr, "phonebook: %v\n", err)
+		return exitError
+	}
+}
+
+// usageError wraps a message so runCLI reports it with the usage exit code.
+func usageError(format string, a ...any) error {
+	return fmt.Errorf("%w: %s", errUsage, fmt.Sprintf(format, a...))
+}
+
+// parseFlags parses fs allowing flags and positional arguments to be mixed,
+// returning the positional arguments in order.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(os.Stderr)
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, usageError("%v", err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// parseQueryFlags is parseFlags for commands that take a search query. Words
+// starting with - that aren't flags of fs, such as -tag:archived, are
+// negated query terms and are returned with the positional arguments.
+func parseQueryFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var flags, terms []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			terms = append(terms, args[i+1:]...)
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		name, _, hasValue := strings.Cut(name, "=")
+		f := fs.Lookup(name)
+		switch {
+		case !strings.HasPrefix(arg, "-") || arg == "-":
+			terms = append(terms, arg)
+		case name == "h" || name == "help":
+			flags = append(flags, arg)
+		case f == nil:
+			terms = append(terms, arg)
+		default:
+			flags = append(flags, arg)
+			if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !hasValue && !(ok && b.IsBoolFlag()) && i+1 < len(args) {
+				i++
+				flags = append(flags, args[i])
+			}
+		}
+	}
+	pos, err := parseFlags(fs, flags)
+	if err != nil {
+		return nil, err
+	}
+	return append(pos, terms...), nil
+}
+
+// loadCLIModel loads the store the same way the TUI does, but fails hard on errors.
+func loadCLIModel() (model, error) {
+	m := newModel()
+	if err := m.load(); err != nil {
+		var corrupt *corruptStoreError
+		if errors.As(err, &corrupt) && corrupt.backup != "" {
+			return m, fmt.Errorf("%w\nrun 'phonebook restore' to recover it", err)
+		}
+		return m, err
+	}
+	for _, problem := range configProblems {
+		fmt.Fprintf(os.Stderr, "phonebook: warning: %s\n", problem)
+	}
+	if m.layerErr != nil {
+		fmt.Fprintf(os.Stderr, "phonebook: warning: %v\n", m.layerErr)
+	}
+	m.applyFilter("")
+	return m, nil
+}
+
+// findProject looks up a project outside the trash by case-insensitive name.
+// With fuzzy set, a name that matches nothing exactly falls back to the best
+// fuzzy match.
+func (m *model) findProject(name string, fuzzy bool) (int, error) {
+	found := -1
+	for i, p := range m.projects {
+		if !p.archived() && p.ID != "" && strings.EqualFold(p.ID, name) {
+			return i, nil
+		}
+		if !p.archived() && strings.EqualFold(p.Name, name) {
+			if found >= 0 {
+				return -1, fmt.Errorf("more than one project is named %q", name)
+			}
+			found = i
+		}
+	}
+	if found >= 0 {
+		return found, nil
+	}
+
+	if fuzzy {
+		m.applyFilter(name)
+		if len(m.filteredIdxs) > 0 {
+			return m.filteredIdxs[0], nil
+		}
+	}
+	return -1, fmt.Errorf("no project named %q", name)
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// printProjects writes the projects at idxs as a table or as JSON.
+func printProjects(w io.Writer, m *model, idxs []int, asJSON bool) error {
+	if asJSON {
+		if m.allBooks {
+			// Book isn't part of the stored project, so add it here.
+			type bookProject struct {
+				Book string `json:"book"`
+				Project
+			}
+			out := make([]bookProject, 0, len(idxs))
+			for _, i := range idxs {
+				out = append(out, bookProject{Book: m.projects[i].Book, Project: m.projects[i]})
+			}
+			return writeJSON(w, out)
+		}
+		out := make([]Project, 0, len(idxs))
+		for _, i := range idxs {
+			out = append(out, m.projects[i])
+		}
+		return writeJSON(w, out)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, i := range idxs {
+		p := m.projects[i]
+		if m.allBooks {
+			fmt.Fprintf(tw, "%s\t", p.Book)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", p.Name, strings.Join(p.Tags, ","), p.Path)
+	}
+	return tw.Flush()
+}
+
+func cmdAdd(args []string) error {
+	fs := flag.NewFlagSet("add", flag.ContinueOnError)
+	tag := fs.String("tag", "", "comma-separated project tags")
+	desc := fs.String("desc", "", "project description")
+	opener := fs.String("opener", "", "command used to open the project")
+	asJSON := fs.Bool("json", false, "print the added project as JSON")
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 2 {
+		return usageError("add takes <name> <path>")
+	}
+
+	name := strings.TrimSpace(pos[0])
+	path := strings.TrimSpace(pos[1])
+	if name == "" || path == "" {
+		return usageError("name and path are required")
+	}
+	if v := validatePath(path); v != "" {
+		return fmt.Errorf("cannot add project: %s", strings.TrimSpace(strings.TrimPrefix(v, "⚠")))
+	}
+
+	m, err := loadCLIModel()
+	if err != nil {
+		return err
+	}
+	path = normalizePath(path)
+	if dup := m.findByPath(path, -1); dup >= 0 {
+		return fmt.Errorf("'%s' already points to %s", m.projects[dup].Name, path)
+	}
+	p := Project{
+		Name:        name,
+		Path:        path,
+		Tags:        splitTags(*tag),
+		Description: strings.TrimSpace(*desc),
+		Opener:      strings.TrimSpace(*opener),
+	}
+	if err := m.addProject(p); err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return writeJSON(os.Stdout, m.projects[0])
+	}
+	if parent, ok := m.nested[p.Path]; ok {
+		fmt.Fprintf(os.Stderr, "phonebook: note: %s is inside '%s'\n", p.Path, parent)
+	}
+	fmt.Fprintf(os.Stdout, "Added '%s'\n", p.Name)
+	return nil
+}
+
+func cmdList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print projects as JSON")
+	all := fs.Bool("all-books", false, "list the projects of every book")
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 0 {
+		return usageError("list takes no arguments")
+	}
+
+	m, err := loadCLIModel()
+	if err != nil {
+		return err
+	}
+	if *all {
+		if err := m.showAllBooks(); err != nil {
+			return err
+		}
+		m.applyFilter("")
+	}
+	return printProjects(os.Stdout, &m, m.filteredIdxs, *asJSON)
+}
+
+func cmdRemove(args []string) error {
+	fs := flag.NewFlagSet("rm", flag.ContinueOnError)
+	purge := fs.Bool("purge", false, "delete permanently instead of moving to the trash")
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return usageError("rm takes <name>")
+	}
+
+	m, err := loadCLIModel()
+	if err != nil {
+		return err
+	}
+	idx, err := m.findProject(pos[0], false)
+	if err != nil {
+		return err
+	}
+	name := m.projects[idx].Name
+	if *purge {
+		if err := m.purgeProject(idx); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "Deleted '%s'\n", name)
+		return nil
+	}
+	if err := m.deleteProject(idx); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "Moved '%s' to the trash\n", name)
+	return nil
+}
+
+func cmdSearch(args []string) error {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print matches as JSON")
+	pos, err := parseQueryFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) == 0 {
+		return usageError("search takes <query>")
+	}
+
+	m, err := loadCLIModel()
+	if err != nil {
+		return err
+	}
+	query := strings.Join(pos, " ")
+	m.applyFilter(query)
+	if err := printProjects(os.Stdout, &m, m.filteredIdxs, *asJSON); err != nil {
+		return err
+	}
+	if len(m.filteredIdxs) == 0 {
+		return fmt.Errorf("no projects match %q", query)
+	}
+	return nil
+}
+
+func cmdPath(args []string) error {
+	fs := flag.NewFlagSet("path", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the project as JSON")
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return usageError("path takes <name>")
+	}
+
+	m, err := loadCLIModel()
+	if err != nil {
+		return err
+	}
+	idx, err := m.findProject(pos[0], true)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return writeJSON(os.Stdout, m.projects[idx])
+	}
+	fmt.Fprintln(os.Stdout, m.projects[idx].Path)
+	return nil
+}
+
+func cmdOpen(args []string) error {
+	fs := flag.NewFlagSet("open", flag.ContinueOnError)
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return usageError("open takes <name>")
+	}
+
+	m, err := loadCLIModel()
+	if err != nil {
+		return err
+	}
+	idx, err := m.findProject(pos[0], true)
+	if err != nil {
+		return err
+	}
+	return openProject(m.projects[idx], m.config, func() error {
+		return m.markOpened(idx)
+	})
+}
+
+func cmdRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) > 1 {
+		return usageError("restore takes at most one backup file")
+	}
+
+	m := newModel()
+	backup := ""
+	if len(pos) == 1 {
+		backup = pos[0]
+	} else if backup, err = latestValidBackup(m.projectsFile); err != nil {
+		return err
+	}
+	if err := m.recoverFromBackup(backup); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "Restored %d projects from %s\n", len(m.projects), backup)
+	return nil
+}
+
+func cmdScan(args []string) error {
+	fs := flag.NewFlagSet("scan", flag.ContinueOnError)
+	depth := fs.Int("depth", 0, "directory levels to descend below each root")
+	doImport := fs.Bool("import", false, "add every discovered project")
+	asJSON := fs.Bool("json", false, "print discovered projects as JSON")
+	roots, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	m, err := loadCLIModel()
+	if err != nil {
+		return err
+	}
+	if len(roots) == 0 {
+		roots = m.config.scanRoots()
+	}
+	if *depth <= 0 {
+		*depth = m.config.scanDepth()
+	}
+
+	candidates, err := scanForProjects(roots, *depth, m.knownPaths())
+	if err != nil {
+		return err
+	}
+	found := make([]Project, len(candidates))
+	for i, c := range candidates {
+		found[i] = c.project
+	}
+
+	if *asJSON {
+		if err := writeJSON(os.Stdout, found); err != nil {
+			return err
+		}
+	} else {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		for _, p := range found {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", p.Name, strings.Join(p.Tags, ","), p.Path)
+		}
+		tw.Flush()
+	}
+
+	if !*doImport || len(found) == 0 {
+		return nil
+	}
+	if err := m.addProjects(found); err != nil {
+		return err
+	}
+	if !*asJSON {
+		fmt.Fprintf(os.Stdout, "Imported %d projects\n", len(found))
+	}
+	return nil
+}
+
+func cmdRetag(args []string) error {
+	fs := flag.NewFlagSet("retag", flag.ContinueOnError)
+	replace := fs.Bool("replace", false, "replace existing tags instead of adding to them")
+	dryRun := fs.Bool("dry-run", false, "show changes without saving them")
+	names, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	m, err := loadCLIModel()
+	if err != nil {
+		return err
+	}
+
+	var idxs []int
+	if len(names) == 0 {
+		for _, idx := range m.filteredIdxs {
+			if m.projects[idx].Shared == "" {
+				idxs = append(idxs, idx)
+			}
+		}
+	} else {
+		for _, name := range names {
+			idx, err := m.findProject(name, false)
+			if err != nil {
+				return err
+			}
+			if err := m.guardShared(idx); err != nil {
+				return err
+			}
+			idxs = append(idxs, idx)
+		}
+	}
+
+	changed := 0
+	for _, idx := range idxs {
+		p := &m.projects[idx]
+		detected := detectStack(p.Path)
+		tags := detected
+		if !*replace {
+			tags = splitTags(strings.Join(append(append([]string{}, p.Tags...), detected...), ","))
+		}
+		if strings.Join(tags, ",") == strings.Join(p.Tags, ",") {
+			continue
+		}
+		fmt.Fprintf(os.Stdout, "%s: %s -> %s\n", p.Name, strings.Join(p.Tags, ", "), strings.Join(tags, ", "))
+		p.Tags = tags
+		p.UpdatedAt = time.Now()
+		changed++
+	}
+
+	if changed == 0 {
+		fmt.Fprintln(os.Stdout, "All tags up to date")
+		return nil
+	}
+	if *dryRun {
+		return nil
+	}
+	return m.saveProjects()
+}
+
+func cmdTrash(args []string) error {
+	fs := flag.NewFlagSet("trash", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print trashed projects as JSON")
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	action := "list"
+	if len(pos) > 0 {
+		action, pos = pos[0], pos[1:]
+	}
+
+	m, err := loadCLIModel()
+	if err != nil {
+		return err
+	}
+	m.refreshTrash()
+
+	findTrashed := func(name string) (int, error) {
+		for _, i := range m.trashIdxs {
+			if strings.EqualFold(m.projects[i].Name, name) {
+				return i, nil
+			}
+		}
+		return -1, fmt.Errorf("no project named %q in the trash", name)
+	}
+
+	switch action {
+	case "list", "ls":
+		if len(pos) != 0 {
+			return usageError("trash list takes no arguments")
+		}
+		return printProjects(os.Stdout, &m, m.trashIdxs, *asJSON)
+	case "restore":
+		if len(pos) != 1 {
+			return usageError("trash restore takes <name>")
+		}
+		idx, err := findTrashed(pos[0])
+		if err != nil {
+			return err
+		}
+		if err := m.restoreProject(idx); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "Restored '%s'\n", m.projects[idx].Name)
+		return nil
+	case "purge":
+		switch len(pos) {
+		case 0:
+			n, err := m.emptyTrash()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stdout, "Purged %d projects\n", n)
+			return nil
+		case 1:
+			idx, err := findTrashed(pos[0])
+			if err != nil {
+				return err
+			}
+			name := m.projects[idx].Name
+			if err := m.purgeProject(idx); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stdout, "Purged '%s'\n", name)
+			return nil
+		}
+		return usageError("trash purge takes at most one <name>")
+	}
+	return usageError("unknown trash action %q (want list, restore or purge)", action)
+}
+
+func cmdDoctor(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fix := fs.Bool("fix", false, "relocate projects with one best match and merge duplicates")
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 0 {
+		return usageError("doctor takes no arguments")
+	}
+
+	m, err := loadCLIModel()
+	if err != nil {
+		return err
+	}
+	roots, depth := m.config.scanRoots(), m.config.scanDepth()
+
+	broken, fixed := 0, 0
+	for i, p := range m.projects {
+		if p.archived() || !m.missing[p.Path] {
+			continue
+		}
+		broken++
+		fmt.Fprintf(os.Stdout, "%s: %s is missing\n", p.Name, p.Path)
+		if p.Shared != "" {
+			fmt.Fprintf(os.Stdout, "  shared from %s; set path_overrides[%q] in %s\n", p.Shared, p.Name, configPath())
+			continue
+		}
+
+		found, err := findRelocations(p, roots, depth)
+		if err != nil {
+			return err
+		}
+		if len(found) == 0 {
+			fmt.Fprintln(os.Stdout, "  no candidates found")
+			continue
+		}
+		for _, r := range found {
+			fmt.Fprintf(os.Stdout, "  candidate: %s (%s)\n", r.path, r.reason)
+		}
+
+		best, ok := bestRelocation(found)
+		if !*fix {
+			continue
+		}
+		if !ok {
+			fmt.Fprintln(os.Stdout, "  not fixed: more than one equally good candidate")
+			continue
+		}
+		m.projects[i].Path = best.path
+		if remote := repoRemote(best.path); remote != "" {
+			m.projects[i].Remote = remote
+		}
+		m.projects[i].UpdatedAt = time.Now()
+		fmt.Fprintf(os.Stdout, "  fixed: now %s\n", best.path)
+		fixed++
+	}
+
+	for _, p := range m.projects {
+		if parent, ok := m.nested[p.Path]; ok && !p.archived() {
+			fmt.Fprintf(os.Stdout, "%s: inside '%s'\n", p.Name, parent)
+		}
+	}
+
+	// Duplicates are merged into the oldest entry. Relocations above may have
+	// created new ones, so groups are computed after them.
+	groups := duplicateGroups(m.projects)
+	for _, g := range groups {
+		names := make([]string, len(g))
+		for j, i := range g {
+			names[j] = m.projects[i].Name
+		}
+		fmt.Fprintf(os.Stdout, "%s: registered as %s\n", m.projects[g[0]].Path, strings.Join(names, ", "))
+	}
+	broken += len(groups)
+	if *fix {
+		// Merging shifts indices, so look the groups up again each time.
+		for g := groups; len(g) > 0; g = duplicateGroups(m.projects) {
+			keep := m.projects[g[0][0]]
+			if err := m.mergeDuplicates(g[0][0], g[0]); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stdout, "merged %d entries into '%s'\n", len(g[0]), keep.Name)
+			fixed++
+		}
+	}
+
+	if broken == 0 {
+		fmt.Fprintln(os.Stdout, "No missing or duplicate projects")
+		return nil
+	}
+	if fixed > 0 {
+		if err := m.saveProjects(); err != nil {
+			return err
+		}
+	}
+	if remaining := broken - fixed; remaining > 0 {
+		return fmt.Errorf("%d of %d problems need attention", remaining, broken)
+	}
+	return nil
+}
+
+func cmdMerge(args []string) error {
+	fs := flag.NewFlagSet("merge", flag.ContinueOnError)
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) < 2 {
+		return usageError("merge takes <keep> <name>...")
+	}
+
+	m, err := loadCLIModel()
+	if err != nil {
+		return err
+	}
+	keep, err := m.findProject(pos[0], false)
+	if err != nil {
+		return err
+	}
+	var others []int
+	for _, name := range pos[1:] {
+		idx, err := m.findProject(name, false)
+		if err != nil {
+			return err
+		}
+		if idx == keep {
+			return usageError("cannot merge '%s' into itself", m.projects[idx].Name)
+		}
+		others = append(others, idx)
+	}
+	name := m.projects[keep].Name
+	if err := m.mergeDuplicates(keep, others); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "Merged %d projects into '%s'\n", len(others), name)
+	return nil
+}
+
+func cmdImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "import every found directory without asking")
+	selectFlag := fs.Bool("select", false, "choose what to import in an interactive checklist")
+	asJSON := fs.Bool("json", false, "print the preview as JSON")
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) == 0 || len(pos) > 2 {
+		var names []string
+		for _, s := range importSources {
+			names = append(names, s.name)
+		}
+		return usageError("import takes <source> [file], where source is one of %s", strings.Join(names, ", "))
+	}
+	src, ok := findImportSource(pos[0])
+	if !ok {
+		return usageError("unknown import source %q", pos[0])
+	}
+	arg := ""
+	if len(pos) == 2 {
+		arg = pos[1]
+	}
+
+	entries, err := src.read(arg)
+	if err != nil {
+		return err
+	}
+	if *selectFlag {
+		// The model's widgets take their styles when it is built.
+		setupTheme(os.Stdout)
+	}
+	m, err := loadCLIModel()
+	if err != nil {
+		return err
+	}
+	candidates := importCandidates(entries, m.knownPaths())
+	if len(candidates) == 0 {
+		fmt.Fprintf(os.Stdout, "Nothing new to import from %s\n", src.name)
+		return nil
+	}
+
+	if *selectFlag {
+		m.mode = viewScan
+		m.scanTitle = "📥 Import from " + src.name
+		m.scanResults = candidates
+		m.scanQuit = true
+		_, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+		return err
+	}
+
+	var picked []Project
+	for _, c := range candidates {
+		if c.selected {
+			picked = append(picked, c.project)
+		}
+	}
+	if *asJSON {
+		if err := writeJSON(os.Stdout, picked); err != nil {
+			return err
+		}
+	} else {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		for _, c := range candidates {
+			mark := "+"
+			if !c.selected {
+				mark = "-"
+			}
+			fmt.Fprintf(tw, "%s %s\t%s\t%s\n", mark, c.project.Name, strings.Join(c.project.Tags, ","), c.project.Path)
+		}
+		tw.Flush()
+		if skipped := len(candidates) - len(picked); skipped > 0 {
+			fmt.Fprintf(os.Stdout, "%d directories marked - don't exist and will be skipped\n", skipped)
+		}
+	}
+	if len(picked) == 0 {
+		return nil
+	}
+
+	if !*yes {
+		if !isTerminal(os.Stdin) || *asJSON {
+			fmt.Fprintln(os.Stderr, "phonebook: preview only; run again with --yes to import")
+			return nil
+		}
+		fmt.Fprintf(os.Stdout, "Import %d projects? [y/N] ", len(picked))
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			fmt.Fprintln(os.Stdout, "Cancelled")
+			return nil
+		}
+	}
+	if err := m.addProjects(picked); err != nil {
+		return err
+	}
+	if !*asJSON {
+		fmt.Fprintf(os.Stdout, "Imported %d projects\n", len(picked))
+	}
+	return nil
+}
+
+// isTerminal reports whether f is an interactive terminal.
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(f.Fd())
+}
+
+func cmdExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "", "csv, yaml, toml, markdown or json (default: from --out, else json)")
+	out := fs.String("out", "", "write to this file instead of stdout")
+	pos, err := parseQueryFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *format == "" {
+		*format = formatFromExt(*out)
+	}
+	if *format == "" {
+		*format = "json"
+	}
+	if formatExt(*format) == "" {
+		return usageError("unknown format %q (want csv, yaml, toml, markdown or json)", *format)
+	}
+
+	m, err := loadCLIModel()
+	if err != nil {
+		return err
+	}
+	if len(pos) > 0 {
+		m.applyFilter(strings.Join(pos, " "))
+	}
+	ps := make([]Project, len(m.filteredIdxs))
+	for i, idx := range m.filteredIdxs {
+		ps[i] = m.projects[idx]
+	}
+
+	if *out == "" {
+		return exportProjects(os.Stdout, ps, *format)
+	}
+	var buf strings.Builder
+	if err := exportProjects(&buf, ps, *format); err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out, []byte(buf.String()), 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d projects to %s\n", len(ps), *out)
+	return nil
+}
+
+func cmdSync(args []string) error {
+	fs := flag.NewFlagSet("sync", flag.ContinueOnError)
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(pos) > 0 && pos[0] == "init" {
+		if len(pos) > 2 {
+			return usageError("sync init takes at most one remote")
+		}
+		remote := ""
+		if len(pos) == 2 {
+			remote = pos[1]
+		}
+		if err := initSync(remote); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "Phonebooks in %s are now committed on every save\n", storeDir())
+		if remote == "" {
+			return nil
+		}
+		pos = nil
+	}
+	if len(pos) != 0 {
+		return usageError("unknown sync action %q", pos[0])
+	}
+
+	res, err := runSync()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stdout, res)
+	return nil
+}
+
+func cmdConfig(args []string) error {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 0 {
+		return usageError("config takes no arguments")
+	}
+
+	path := configPath()
+	note := ""
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		note = " (not found; using defaults)"
+	}
+	fmt.Fprintf(os.Stdout, "config  %s%s\n", path, note)
+	fmt.Fprintf(os.Stdout, "store   %s\n", storeDir())
+	if len(configProblems) == 0 {
+		return nil
+	}
+	fmt.Fprintln(os.Stdout)
+	for _, problem := range configProblems {
+		fmt.Fprintln(os.Stdout, problem)
+	}
+	return fmt.Errorf("%d problems in %s", len(configProblems), filepath.Base(path))
+}
+
+func cmdBooks(args []string) error {
+	fs := flag.NewFlagSet("books", flag.ContinueOnError)
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 0 {
+		return usageError("books takes no arguments")
+	}
+
+	current := activeBook()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, name := range listBooks() {
+		b := newModel()
+		count := "?"
+		if err := b.setBook(name); err == nil {
+			count = fmt.Sprintf("%d", b.activeCount())
+		}
+		mark := " "
+		if name == current {
+			mark = "*"
+		}
+		fmt.Fprintf(tw, "%s %s\t%s\t%s\n", mark, name, count, bookFile(name))
+	}
+	return tw.Flush()
+}
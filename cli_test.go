@@ -0,0 +1,168 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// runCLIOutput runs a subcommand and returns its exit code and what it
+// wrote to stdout and stderr.
+func runCLIOutput(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	capture := func(f **os.File) func() string {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		orig := *f
+		*f = w
+		done := make(chan string)
+		go func() {
+			data, _ := io.ReadAll(r)
+			done <- string(data)
+		}()
+		return func() string {
+			w.Close()
+			*f = orig
+			return <-done
+		}
+	}
+	stdout, stderr := capture(&os.Stdout), capture(&os.Stderr)
+	code := runCLI(args)
+	return code, stdout(), stderr()
+}
+
+// cliStore sets up a home directory whose default phonebook holds projects.
+func cliStore(t *testing.T, projects ...Project) {
+	t.Helper()
+	withHome(t)
+	m := newModel()
+	if err := m.load(); err != nil {
+		t.Fatal(err)
+	}
+	m.projects = projects
+	if err := m.saveProjects(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseQueryFlags(t *testing.T) {
+	tests := []struct {
+		args     []string
+		wantPos  []string
+		wantJSON bool
+		wantErr  bool
+	}{
+		{[]string{"api"}, []string{"api"}, false, false},
+		{[]string{"-tag:archived"}, []string{"-tag:archived"}, false, false},
+		{[]string{"--json", "tag:go", "-path:/tmp"}, []string{"tag:go", "-path:/tmp"}, true, false},
+		{[]string{"-is:trashed", "-json"}, []string{"-is:trashed"}, true, false},
+		{[]string{"--json=false", "api"}, []string{"api"}, false, false},
+		{[]string{"-web", "--", "--json"}, []string{"-web", "--json"}, false, false},
+		{[]string{"--json=maybe"}, nil, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			fs := flag.NewFlagSet("search", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			asJSON := fs.Bool("json", false, "")
+			pos, err := parseQueryFlags(fs, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !slices.Equal(pos, tt.wantPos) || *asJSON != tt.wantJSON {
+				t.Errorf("got %q, json %v; want %q, json %v", pos, *asJSON, tt.wantPos, tt.wantJSON)
+			}
+		})
+	}
+}
+
+func TestCLISearch(t *testing.T) {
+	api, blog, cli := testProject("api", t0), testProject("blog", t1), testProject("cli", t2)
+	api.Tags, blog.Tags, cli.Tags = []string{"go", "archived"}, []string{"web"}, []string{"go"}
+	cliStore(t, api, blog, cli)
+
+	tests := []struct {
+		args     []string
+		want     []string
+		wantCode int
+	}{
+		{[]string{"search", "tag:go"}, []string{"api", "cli"}, exitOK},
+		{[]string{"search", "-tag:archived"}, []string{"blog", "cli"}, exitOK},
+		{[]string{"search", "tag:go", "-tag:archived", "--json"}, []string{"cli"}, exitOK},
+		{[]string{"search", "-tag:go", "-tag:web"}, nil, exitError},
+		{[]string{"search", "--json"}, nil, exitUsage},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args[1:], " "), func(t *testing.T) {
+			code, out, stderr := runCLIOutput(t, tt.args...)
+			if code != tt.wantCode {
+				t.Fatalf("exit code %d, want %d (stderr %q)", code, tt.wantCode, stderr)
+			}
+			var got []string
+			for _, name := range []string{"api", "blog", "cli"} {
+				if strings.Contains(out, "/src/"+name) {
+					got = append(got, name)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("found %v, want %v\n%s", got, tt.want, out)
+			}
+		})
+	}
+}
+
+func TestCLIExportQuery(t *testing.T) {
+	api, blog := testProject("api", t0), testProject("blog", t1)
+	api.Tags = []string{"archived"}
+	cliStore(t, api, blog)
+
+	code, out, stderr := runCLIOutput(t, "export", "-tag:archived", "--format", "csv")
+	if code != exitOK {
+		t.Fatalf("exit code %d (stderr %q)", code, stderr)
+	}
+	if !strings.Contains(out, "/src/blog") || strings.Contains(out, "/src/api") {
+		t.Errorf("exported\n%s\nwant only blog", out)
+	}
+}
+
+func TestCLIOpenRecordsOnlyRealOpens(t *testing.T) {
+	dir := t.TempDir()
+	present, missing, failing := testProject("present", t0), testProject("missing", t1), testProject("failing", t2)
+	present.Path, present.Opener = dir, "true"
+	missing.Path, missing.Opener = filepath.Join(dir, "gone"), "true"
+	failing.Path, failing.Opener = dir, "phonebook-no-such-opener"
+	cliStore(t, present, missing, failing)
+
+	tests := []struct {
+		name      string
+		wantCode  int
+		wantOpens int
+	}{
+		{"present", exitOK, 1},
+		{"missing", exitError, 0},
+		{"failing", exitError, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, _, stderr := runCLIOutput(t, "open", tt.name); code != tt.wantCode {
+				t.Errorf("exit code %d, want %d (stderr %q)", code, tt.wantCode, stderr)
+			}
+			m := newModel()
+			if err := m.load(); err != nil {
+				t.Fatal(err)
+			}
+			if got := m.projects[indexOfName(t, &m, tt.name)].OpenCount; got != tt.wantOpens {
+				t.Errorf("open count %d, want %d", got, tt.wantOpens)
+			}
+		})
+	}
+}
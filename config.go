@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Config holds user preferences, read from config.toml at startup.
+type Config struct {
+	// StoreDir is where projects.json, books/ and their backups live.
+	// Defaults to $XDG_DATA_HOME/phonebook.
+	StoreDir string `json:"store_dir,omitempty" toml:"store_dir"`
+	// Opener is the global command template used to open projects.
+	Opener string `json:"opener,omitempty" toml:"opener"`
+	// TagOpeners maps a tag to the command template used for projects with that tag.
+	TagOpeners map[string]string `json:"tag_openers,omitempty" toml:"tag_openers"`
+	// DefaultBook is the book used when neither --book nor PHONEBOOK_BOOK is set.
+	DefaultBook string `json:"default_book,omitempty" toml:"default_book"`
+	// ListWidth is the width of the project list in columns.
+	ListWidth int `json:"list_width,omitempty" toml:"list_width"`
+	// ScanRoots are the directories searched for projects by scan.
+	ScanRoots []string `json:"scan_roots,omitempty" toml:"scan_roots"`
+	// ScanDepth is how many levels below each root scan descends.
+	ScanDepth int `json:"scan_depth,omitempty" toml:"scan_depth"`
+	// ConfirmDelete asks before deleting a project. Defaults to true.
+	ConfirmDelete *bool `json:"confirm_delete,omitempty" toml:"confirm_delete"`
+	// Theme picks a theme by name ("name" key) and overrides its colors,
+	// e.g. primary = "#FF8800".
+	Theme map[string]string `json:"theme,omitempty" toml:"theme"`
+	// Themes are user-defined themes: colors on top of a built-in "base".
+	Themes map[string]map[string]string `json:"themes,omitempty" toml:"themes"`
+	// Keys rebinds actions by name to a key or a list of keys, e.g.
+	// delete = "x". List-view actions sit in the table itself, search and
+	// form actions in its "filter" and "form" tables.
+	Keys map[string]any `json:"keys,omitempty" toml:"keys"`
+	// Layers are read-only project files, such as a team list, shown along
+	// with the user's own projects.
+	Layers []LayerConfig `json:"layers,omitempty" toml:"layers"`
+	// SharedRoot is what relative paths in layers are resolved against when a
+	// layer doesn't set its own root. Defaults to the home directory.
+	SharedRoot string `json:"shared_root,omitempty" toml:"shared_root"`
+	// PathOverrides maps the name of a shared project to where it lives on
+	// this machine, absolute or relative to the layer's root.
+	PathOverrides map[string]string `json:"path_overrides,omitempty" toml:"path_overrides"`
+}
+
+// LayerConfig names a read-only project file.
+type LayerConfig struct {
+	// File is a projects file in any format import understands.
+	File string `json:"file" toml:"file"`
+	// Root overrides SharedRoot for this layer.
+	Root string `json:"root,omitempty" toml:"root"`
+}
+
+// defaultListWidth is the width of the project list unless configured.
+const defaultListWidth = 45
+
+// userConfig is the configuration loaded by setupConfig. configProblems
+// lists what was wrong with it; the affected settings keep their defaults.
+var (
+	userConfig     Config
+	configProblems []string
+)
+
+func (c Config) confirmDelete() bool {
+	return c.ConfirmDelete == nil || *c.ConfirmDelete
+}
+
+func (c Config) listWidth() int {
+	if c.ListWidth == 0 {
+		return defaultListWidth
+	}
+	return c.ListWidth
+}
+
+// xdgDir returns $<env>/phonebook, or ~/<fallback>/phonebook when the
+// variable is unset. Relative values are ignored, as the spec requires.
+func xdgDir(env, fallback string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "phonebook")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, fallback, "phonebook")
+}
+
+// legacyDir is where versions before config.toml kept both the config and
+// the projects.
+func legacyDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "projects")
+}
+
+// configPath returns the config file to read: config.toml in the XDG config
+// directory or, if there is none and an older config.json exists, that.
+func configPath() string {
+	path := filepath.Join(xdgDir("XDG_CONFIG_HOME", ".config"), "config.toml")
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		legacy := filepath.Join(legacyDir(), "config.json")
+		if _, err := os.Stat(legacy); err == nil {
+			return legacy
+		}
+	}
+	return path
+}
+
+// loadConfig reads the config file at path, as TOML or, for config.json,
+// JSON. A missing file yields the defaults. Keys that mean nothing to us are
+// reported as problems rather than silently ignored.
+func loadConfig(path string) (Config, []string, error) {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil, nil
+		}
+		return cfg, nil, err
+	}
+	if strings.HasSuffix(path, ".json") {
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return Config{}, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return cfg, nil, nil
+	}
+	md, err := toml.Decode(string(data), &cfg)
+	if err != nil {
+		return Config{}, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	var unknown []string
+	for _, key := range md.Undecoded() {
+		if key[0] == "keys" {
+			// Bindings are decoded loosely and checked by validateKeys.
+			continue
+		}
+		unknown = append(unknown, fmt.Sprintf("unknown setting %q", key.String()))
+	}
+	return cfg, unknown, nil
+}
+
+// setupConfig loads and validates the user's config and applies the key
+// bindings; setupTheme applies the colors once the UI is about to start.
+// Problems don't stop phonebook from starting; they are shown once it has.
+func setupConfig() {
+	path := configPath()
+	cfg, problems, err := loadConfig(path)
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+	problems = append(problems, cfg.validate()...)
+	for i, p := range problems {
+		if !strings.HasPrefix(p, path) {
+			problems[i] = filepath.Base(path) + ": " + p
+		}
+	}
+	userConfig, configProblems = cfg, problems
+
+	applyKeys(cfg.Keys)
+}
+
+// configSummary describes configProblems in one line for the status bar.
+func configSummary() string {
+	switch len(configProblems) {
+	case 0:
+		return ""
+	case 1:
+		return configProblems[0]
+	}
+	return fmt.Sprintf("%s (and %d more problems; run 'phonebook config')", configProblems[0], len(configProblems)-1)
+}
+
+// validate checks the config, resets invalid settings to their defaults and
+// returns a description of each problem found.
+func (c *Config) validate() []string {
+	var problems []string
+	report := func(format string, a ...any) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+	}
+
+	if c.StoreDir != "" {
+		// expandPath would resolve a relative path against the working
+		// directory, so check what was written.
+		absolute := filepath.IsAbs(c.StoreDir) || strings.HasPrefix(c.StoreDir, "~")
+		if info, err := os.Stat(expandPath(c.StoreDir)); !absolute || (err == nil && !info.IsDir()) {
+			report("store_dir %s is not an absolute directory path; using the default", c.StoreDir)
+			c.StoreDir = ""
+		}
+	}
+	if c.DefaultBook != "" {
+		if err := validateBookName(c.DefaultBook); err != nil {
+			report("default_book: %v", err)
+			c.DefaultBook = ""
+		}
+	}
+	if c.ListWidth != 0 && (c.ListWidth < 30 || c.ListWidth > 120) {
+		report("list_width %d is outside 30–120; using %d", c.ListWidth, defaultListWidth)
+		c.ListWidth = 0
+	}
+	if c.ScanDepth < 0 {
+		report("scan_depth can't be negative; using %d", defaultScanDepth)
+		c.ScanDepth = 0
+	}
+	for _, root := range c.ScanRoots {
+		if _, err := os.Stat(expandPath(root)); err != nil {
+			report("scan root %s does not exist", root)
+		}
+	}
+
+	checkOpener := func(name, tmpl string) bool {
+		if _, err := splitCommand(tmpl); err != nil {
+			report("%s: %v", name, err)
+			return false
+		}
+		return true
+	}
+	if c.Opener != "" && !checkOpener("opener", c.Opener) {
+		c.Opener = ""
+	}
+	for tag, tmpl := range c.TagOpeners {
+		if !checkOpener(fmt.Sprintf("tag_openers.%s", tag), tmpl) {
+			delete(c.TagOpeners, tag)
+		}
+	}
+	for i, layer := range c.Layers {
+		if layer.File == "" {
+			report("layers[%d] has no file", i)
+		}
+	}
+
+	problems = append(problems, validateThemes(c)...)
+	problems = append(problems, validateKeys(c.Keys)...)
+	return problems
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name         string
+		file, data   string
+		check        func(Config) bool
+		wantProblems int
+		wantErr      bool
+	}{
+		{
+			"toml",
+			"config.toml", "opener = \"vim {path}\"\nscan_depth = 2\n[tag_openers]\ngo = \"goland {path}\"\n",
+			func(c Config) bool {
+				return c.Opener == "vim {path}" && c.ScanDepth == 2 && c.TagOpeners["go"] == "goland {path}"
+			},
+			0, false,
+		},
+		{
+			"layers",
+			"config.toml", "[[layers]]\nfile = \"~/team.json\"\nroot = \"~/work\"\n",
+			func(c Config) bool { return len(c.Layers) == 1 && c.Layers[0].Root == "~/work" },
+			0, false,
+		},
+		{
+			"unknown settings are reported",
+			"config.toml", "opener = \"vim\"\nopen_with = \"code\"\n[theme]\nprimary = \"#fff\"\n[colours]\nx = 1\n",
+			func(c Config) bool { return c.Opener == "vim" && c.Theme["primary"] == "#fff" },
+			3, false,
+		},
+		{
+			"legacy json",
+			"config.json", `{"opener": "vim {path}", "scan_roots": ["~/src"]}`,
+			func(c Config) bool { return c.Opener == "vim {path}" && len(c.ScanRoots) == 1 },
+			0, false,
+		},
+		{"malformed toml", "config.toml", "opener = \"vim\n", nil, 0, true},
+		{"malformed json", "config.json", `{"opener":`, nil, 0, true},
+		{"wrong type", "config.toml", "scan_depth = \"deep\"\n", nil, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name, tt.file)
+			os.MkdirAll(filepath.Dir(path), 0o755)
+			if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			cfg, problems, err := loadConfig(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadConfig() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !tt.check(cfg) {
+				t.Errorf("loadConfig() = %+v", cfg)
+			}
+			if len(problems) != tt.wantProblems {
+				t.Errorf("problems %q, want %d", problems, tt.wantProblems)
+			}
+		})
+	}
+
+	if cfg, problems, err := loadConfig(filepath.Join(dir, "missing.toml")); err != nil || problems != nil || cfg.Opener != "" {
+		t.Errorf("loadConfig() of a missing file = %+v, %v, %v; want the defaults", cfg, problems, err)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(file, nil, 0o644)
+
+	tests := []struct {
+		name  string
+		cfg   Config
+		want  string // Part of the single problem expected, or "" for none
+		check func(Config) bool
+	}{
+		{"defaults", Config{}, "", nil},
+		{"relative store_dir", Config{StoreDir: "data"}, "store_dir", func(c Config) bool { return c.StoreDir == "" }},
+		{"store_dir is a file", Config{StoreDir: file}, "store_dir", func(c Config) bool { return c.StoreDir == "" }},
+		{"bad default_book", Config{DefaultBook: "../x"}, "default_book", func(c Config) bool { return c.DefaultBook == "" }},
+		{"list_width too small", Config{ListWidth: 10}, "list_width", func(c Config) bool { return c.listWidth() == defaultListWidth }},
+		{"list_width", Config{ListWidth: 60}, "", func(c Config) bool { return c.listWidth() == 60 }},
+		{"negative scan_depth", Config{ScanDepth: -1}, "scan_depth", func(c Config) bool { return c.ScanDepth == 0 }},
+		{"missing scan root", Config{ScanRoots: []string{file + "/nope"}}, "scan root", nil},
+		{"bad opener", Config{Opener: `code "{path}`}, "opener", func(c Config) bool { return c.Opener == "" }},
+		{"bad tag opener", Config{TagOpeners: map[string]string{"go": `vim '`}}, "tag_openers.go", func(c Config) bool { return len(c.TagOpeners) == 0 }},
+		{"layer without a file", Config{Layers: []LayerConfig{{Root: "~"}}}, "layers[0]", nil},
+		{"unknown color", Config{Theme: map[string]string{"pink": "#f0f"}}, "unknown color", func(c Config) bool { return len(c.Theme) == 0 }},
+		{"bad color", Config{Theme: map[string]string{"primary": "red"}}, "not a color", func(c Config) bool { return len(c.Theme) == 0 }},
+		{"colors", Config{Theme: map[string]string{"primary": "#FF8800", "muted": "240"}}, "", func(c Config) bool { return len(c.Theme) == 2 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := tt.cfg.validate()
+			switch {
+			case tt.want == "" && len(problems) > 0:
+				t.Errorf("validate() = %q, want no problems", problems)
+			case tt.want != "" && (len(problems) != 1 || !strings.Contains(problems[0], tt.want)):
+				t.Errorf("validate() = %q, want one about %s", problems, tt.want)
+			}
+			if tt.check != nil && !tt.check(tt.cfg) {
+				t.Errorf("config after validate() = %+v", tt.cfg)
+			}
+		})
+	}
+}
+
+func TestDirectories(t *testing.T) {
+	home := withHome(t)
+	data := filepath.Join(home, ".local", "share", "phonebook")
+	if got := storeDir(); got != data {
+		t.Errorf("storeDir() = %q, want %q", got, data)
+	}
+	if got, want := configPath(), filepath.Join(home, ".config", "phonebook", "config.toml"); got != want {
+		t.Errorf("configPath() = %q, want %q", got, want)
+	}
+
+	// A store and config left by an older version keep being used.
+	legacy := filepath.Join(home, ".config", "projects")
+	os.MkdirAll(legacy, 0o755)
+	os.WriteFile(filepath.Join(legacy, "projects.json"), []byte("[]"), 0o644)
+	os.WriteFile(filepath.Join(legacy, "config.json"), []byte("{}"), 0o644)
+	if got := storeDir(); got != legacy {
+		t.Errorf("storeDir() with a legacy store = %q, want %q", got, legacy)
+	}
+	if got, want := configPath(), filepath.Join(legacy, "config.json"); got != want {
+		t.Errorf("configPath() with a legacy config = %q, want %q", got, want)
+	}
+
+	// The XDG variables win once they hold a store, but only if absolute.
+	xdg := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", xdg)
+	os.MkdirAll(filepath.Join(xdg, "phonebook"), 0o755)
+	os.WriteFile(filepath.Join(xdg, "phonebook", "projects.json"), []byte("[]"), 0o644)
+	if got, want := storeDir(), filepath.Join(xdg, "phonebook"); got != want {
+		t.Errorf("storeDir() with XDG_DATA_HOME = %q, want %q", got, want)
+	}
+	t.Setenv("XDG_DATA_HOME", "relative")
+	if got := storeDir(); got != legacy {
+		t.Errorf("storeDir() with a relative XDG_DATA_HOME = %q, want %q", got, legacy)
+	}
+
+	userConfig.StoreDir = "~/phonebooks"
+	if got, want := storeDir(), filepath.Join(home, "phonebooks"); got != want {
+		t.Errorf("storeDir() with store_dir = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// detectMaxFiles caps how many files are sampled for extension statistics.
+	detectMaxFiles = 2000
+	// detectMaxDepth limits how deep the extension walk goes.
+	detectMaxDepth = 4
+	// detectMinShare is the fraction of source files a language needs to be tagged.
+	detectMinShare = 0.2
+)
+
+// manifestLanguages maps manifest files to the language they imply.
+var manifestLanguages = []struct {
+	file string
+	lang string
+}{
+	{"go.mod", "go"},
+	{"Cargo.toml", "rust"},
+	{"package.json", "javascript"},
+	{"deno.json", "deno"},
+	{"pyproject.toml", "python"},
+	{"setup.py", "python"},
+	{"requirements.txt", "python"},
+	{"Gemfile", "ruby"},
+	{"pom.xml", "java"},
+	{"build.gradle", "java"},
+	{"build.gradle.kts", "kotlin"},
+	{"composer.json", "php"},
+	{"mix.exs", "elixir"},
+	{"pubspec.yaml", "dart"},
+	{"CMakeLists.txt", "cpp"},
+	{"stack.yaml", "haskell"},
+	{"dune-project", "ocaml"},
+	{"Dockerfile", "docker"},
+}
+
+// frameworkMarkers maps a manifest to dependency names that identify a
+// framework, matched as substrings of the manifest contents.
+var frameworkMarkers = map[string][]struct {
+	dep string
+	tag string
+}{
+	"go.mod": {
+		{"github.com/gin-gonic/gin", "gin"},
+		{"github.com/labstack/echo", "echo"},
+		{"github.com/gofiber/fiber", "fiber"},
+		{"github.com/charmbracelet/bubbletea", "bubbletea"},
+		{"github.com/spf13/cobra", "cobra"},
+	},
+	"Cargo.toml": {
+		{"tokio", "tokio"},
+		{"axum", "axum"},
+		{"actix-web", "actix"},
+		{"rocket", "rocket"},
+		{"bevy", "bevy"},
+		{"tauri", "tauri"},
+	},
+	"pyproject.toml": {
+		{"django", "django"},
+		{"flask", "flask"},
+		{"fastapi", "fastapi"},
+		{"torch", "pytorch"},
+	},
+	"requirements.txt": {
+		{"django", "django"},
+		{"flask", "flask"},
+		{"fastapi", "fastapi"},
+		{"torch", "pytorch"},
+	},
+	"Gemfile": {
+		{"rails", "rails"},
+		{"sinatra", "sinatra"},
+	},
+	"pom.xml": {
+		{"spring-boot", "spring"},
+	},
+	"build.gradle": {
+		{"spring-boot", "spring"},
+	},
+	"composer.json": {
+		{"laravel/framework", "laravel"},
+		{"symfony/", "symfony"},
+	},
+	"mix.exs": {
+		{":phoenix", "phoenix"},
+	},
+	"pubspec.yaml": {
+		{"flutter:", "flutter"},
+	},
+}
+
+// packageJSONFrameworks maps npm dependencies to tags.
+var packageJSONFrameworks = []struct {
+	dep string
+	tag string
+}{
+	{"next", "nextjs"},
+	{"react", "react"},
+	{"nuxt", "nuxt"},
+	{"vue", "vue"},
+	{"@sveltejs/kit", "sveltekit"},
+	{"svelte", "svelte"},
+	{"@angular/core", "angular"},
+	{"express", "express"},
+	{"electron", "electron"},
+	{"vite", "vite"},
+}
+
+// extensionLanguages maps source file extensions to languages.
+var extensionLanguages = map[string]string{
+	".go":     "go",
+	".rs":     "rust",
+	".js":     "javascript",
+	".jsx":    "javascript",
+	".mjs":    "javascript",
+	".ts":     "typescript",
+	".tsx":    "typescript",
+	".py":     "python",
+	".rb":     "ruby",
+	".java":   "java",
+	".kt":     "kotlin",
+	".php":    "php",
+	".ex":     "elixir",
+	".exs":    "elixir",
+	".dart":   "dart",
+	".c":      "c",
+	".h":      "c",
+	".cc":     "cpp",
+	".cpp":    "cpp",
+	".hpp":    "cpp",
+	".cs":     "csharp",
+	".swift":  "swift",
+	".hs":     "haskell",
+	".ml":     "ocaml",
+	".lua":    "lua",
+	".zig":    "zig",
+	".sh":     "shell",
+	".vue":    "vue",
+	".svelte": "svelte",
+}
+
+// tagsDetectedMsg carries detected tags for a path back to the add form.
+type tagsDetectedMsg struct {
+	path string
+	tags []string
+}
+
+func detectTagsCmd(path string) tea.Cmd {
+	return func() tea.Msg {
+		return tagsDetectedMsg{path: path, tags: detectStack(expandPath(path))}
+	}
+}
+
+// detectStack guesses the languages and frameworks used in dir from its
+// manifest files and the mix of source file extensions.
+func detectStack(dir string) []string {
+	var tags []string
+
+	for _, ml := range manifestLanguages {
+		data, err := os.ReadFile(filepath.Join(dir, ml.file))
+		if err != nil {
+			continue
+		}
+		lang := ml.lang
+		if ml.file == "package.json" {
+			deps := packageJSONDeps(data)
+			if deps["typescript"] {
+				lang = "typescript"
+			}
+			tags = append(tags, lang)
+			for _, fw := range packageJSONFrameworks {
+				if deps[fw.dep] {
+					tags = append(tags, fw.tag)
+				}
+			}
+			continue
+		}
+
+		tags = append(tags, lang)
+		content := strings.ToLower(string(data))
+		for _, fw := range frameworkMarkers[ml.file] {
+			if strings.Contains(content, strings.ToLower(fw.dep)) {
+				tags = append(tags, fw.tag)
+			}
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tsconfig.json")); err == nil {
+		tags = append(tags, "typescript")
+	}
+
+	tags = append(tags, dominantLanguages(dir)...)
+	return splitTags(strings.Join(tags, ","))
+}
+
+// packageJSONDeps returns the names of all dependencies in a package.json.
+func packageJSONDeps(data []byte) map[string]bool {
+	var pkg struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	deps := make(map[string]bool)
+	if json.Unmarshal(data, &pkg) != nil {
+		return deps
+	}
+	for d := range pkg.Dependencies {
+		deps[d] = true
+	}
+	for d := range pkg.DevDependencies {
+		deps[d] = true
+	}
+	return deps
+}
+
+// dominantLanguages samples source files under dir and returns languages that
+// make up at least detectMinShare of them, most common first.
+func dominantLanguages(dir string) []string {
+	counts := make(map[string]int)
+	total, seen := 0, 0
+
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if path != dir && (strings.HasPrefix(d.Name(), ".") || skipDirs[d.Name()]) {
+				return filepath.SkipDir
+			}
+			if rel, _ := filepath.Rel(dir, path); rel != "." && strings.Count(rel, string(filepath.Separator))+1 >= detectMaxDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		seen++
+		if seen > detectMaxFiles {
+			return filepath.SkipAll
+		}
+		if lang, ok := extensionLanguages[strings.ToLower(filepath.Ext(d.Name()))]; ok {
+			counts[lang]++
+			total++
+		}
+		return nil
+	})
+
+	if total < 3 {
+		return nil
+	}
+	var langs []string
+	for lang, n := range counts {
+		if float64(n)/float64(total) >= detectMinShare {
+			langs = append(langs, lang)
+		}
+	}
+	sort.Slice(langs, func(i, j int) bool {
+		if counts[langs[i]] != counts[langs[j]] {
+			return counts[langs[i]] > counts[langs[j]]
+		}
+		return langs[i] < langs[j]
+	})
+	return langs
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDetectStack(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{"nothing", map[string]string{"notes.txt": ""}, nil},
+		{"go with a framework", map[string]string{
+			"go.mod":  "module x\nrequire github.com/charmbracelet/bubbletea v1.0.0\n",
+			"main.go": "", "ui.go": "", "keys.go": "",
+		}, []string{"go", "bubbletea"}},
+		{"typescript react app", map[string]string{
+			"package.json": `{"dependencies": {"react": "18", "next": "14"}, "devDependencies": {"typescript": "5"}}`,
+		}, []string{"typescript", "nextjs", "react"}},
+		{"tsconfig", map[string]string{"package.json": `{}`, "tsconfig.json": `{}`}, []string{"javascript", "typescript"}},
+		{"malformed package.json", map[string]string{"package.json": `{`}, []string{"javascript"}},
+		{"python manifests agree", map[string]string{
+			"pyproject.toml":   "dependencies = [\"FastAPI\"]",
+			"requirements.txt": "fastapi\n",
+		}, []string{"python", "fastapi"}},
+		{"languages by extension", map[string]string{
+			"a.rs": "", "b.rs": "", "c.rs": "", "d.rs": "", "build.sh": "",
+			"vendor/x.go": "", "vendor/y.go": "", "vendor/z.go": "",
+			".hidden/q.py": "", ".hidden/r.py": "",
+		}, []string{"rust", "shell"}},
+		{"too few files to tell", map[string]string{"a.py": "", "b.py": ""}, nil},
+		{"minor languages are left out", map[string]string{
+			"1.py": "", "2.py": "", "3.py": "", "4.py": "", "5.py": "", "6.py": "", "7.py": "", "8.py": "", "9.py": "", "x.lua": "",
+		}, []string{"python"}},
+		{"dockerfile", map[string]string{"Dockerfile": "FROM scratch"}, []string{"docker"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTree(t, dir, tt.files)
+			if got := detectStack(dir); !slices.Equal(got, tt.want) {
+				t.Errorf("detectStack() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
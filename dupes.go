@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// normalizePath turns a user-supplied path into the canonical form stored in
+// the phonebook: home-expanded, absolute, clean and with symlinks resolved.
+// Paths that can't be resolved (for example because they don't exist) are
+// still cleaned.
+func normalizePath(path string) string {
+	path = filepath.Clean(expandPath(path))
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
+
+// findByPath returns the index of an active project other than skip whose
+// directory is the same as path, or -1.
+func (m *model) findByPath(path string, skip int) int {
+	want := normalizePath(path)
+	for i, p := range m.projects {
+		// A personal entry may take over the directory of a shared one.
+		if i != skip && !p.archived() && p.Shared == "" && normalizePath(p.Path) == want {
+			return i
+		}
+	}
+	return -1
+}
+
+// normalizedPaths returns the normalized path of every project, with "" for
+// projects in the trash so they never count as duplicates or parents.
+func normalizedPaths(projects []Project) []string {
+	norm := make([]string, len(projects))
+	for i, p := range projects {
+		if !p.archived() {
+			norm[i] = normalizePath(p.Path)
+		}
+	}
+	return norm
+}
+
+// duplicateGroups returns the indices of active projects that point at the
+// same directory, one group per directory, oldest project first.
+func duplicateGroups(projects []Project) [][]int {
+	norm := normalizedPaths(projects)
+	byPath := make(map[string][]int)
+	var order []string
+	for i, key := range norm {
+		if key == "" {
+			continue
+		}
+		if _, ok := byPath[key]; !ok {
+			order = append(order, key)
+		}
+		byPath[key] = append(byPath[key], i)
+	}
+
+	var groups [][]int
+	for _, key := range order {
+		if g := byPath[key]; len(g) > 1 {
+			sort.SliceStable(g, func(a, b int) bool {
+				return projects[g[a]].CreatedAt.Before(projects[g[b]].CreatedAt)
+			})
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+// nestedParent returns the index of the project whose normalized directory
+// most closely contains path, or -1. Both path and norm must already be
+// normalized; a project at exactly path doesn't count.
+func nestedParent(norm []string, path string) int {
+	best, bestLen := -1, 0
+	for i, dir := range norm {
+		if dir == "" {
+			continue
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if len(dir) > bestLen {
+			best, bestLen = i, len(dir)
+		}
+	}
+	return best
+}
+
+// mergeMetadata folds other into keep: tags are combined, empty fields are
+// filled in and the open history of both is kept.
+func mergeMetadata(keep, other Project) Project {
+	keep.Tags = splitTags(strings.Join(append(append([]string{}, keep.Tags...), other.Tags...), ","))
+	if keep.Description == "" {
+		keep.Description = other.Description
+	}
+	if keep.Opener == "" {
+		keep.Opener = other.Opener
+	}
+	if keep.Remote == "" {
+		keep.Remote = other.Remote
+	}
+	if keep.Pinned == 0 || (other.Pinned > 0 && other.Pinned < keep.Pinned) {
+		keep.Pinned = other.Pinned
+	}
+
+	keep.OpenCount += other.OpenCount
+	opens := append(append([]time.Time{}, keep.Opens...), other.Opens...)
+	sort.Slice(opens, func(i, j int) bool { return opens[i].Before(opens[j]) })
+	if len(opens) > maxRecordedOpens {
+		opens = opens[len(opens)-maxRecordedOpens:]
+	}
+	keep.Opens = opens
+	return keep
+}
+
+// mergeDuplicates folds the projects at others into the one at keep and
+// removes them for good; the merged entry carries everything worth keeping.
+func (m *model) mergeDuplicates(keep int, others []int) error {
+	for _, i := range append([]int{keep}, others...) {
+		if err := m.guardShared(i); err != nil {
+			return err
+		}
+	}
+	merged := m.projects[keep]
+	drop := make(map[int]bool, len(others))
+	for _, i := range others {
+		if i == keep {
+			continue
+		}
+		merged = mergeMetadata(merged, m.projects[i])
+		drop[i] = true
+	}
+	merged.UpdatedAt = time.Now()
+	m.projects[keep] = merged
+
+	kept := m.projects[:0]
+	for i, p := range m.projects {
+		if !drop[i] {
+			kept = append(kept, p)
+		}
+	}
+	m.projects = kept
+	m.renumberPins(m.pinOrder())
+	return m.saveProjects()
+}
+
+// mergeSelectedDuplicates merges every project sharing a directory with the
+// project identified by key into it.
+func (m *model) mergeSelectedDuplicates(key string) (string, error) {
+	for _, g := range duplicateGroups(m.projects) {
+		for _, i := range g {
+			if projectKey(m.projects[i]) != key {
+				continue
+			}
+			name := m.projects[i].Name
+			m.checkpoint(fmt.Sprintf("merge into '%s'", name))
+			if err := m.mergeDuplicates(i, g); err != nil {
+				m.dropCheckpoint()
+				return "", err
+			}
+			return fmt.Sprintf("✓ Merged %d duplicates into '%s'", len(g)-1, name), nil
+		}
+	}
+	return "", fmt.Errorf("no duplicates to merge")
+}
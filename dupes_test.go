@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNormalizePath(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "real")
+	link := filepath.Join(dir, "link")
+	if err := os.Mkdir(target, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	tests := []struct {
+		in, want string
+	}{
+		{target, target},
+		{target + "/", target},
+		{link, target},
+		{link + "/../real/./", target},
+		{"~/src", filepath.Join(home, "src")},
+		{dir + "/missing//x/..", filepath.Join(dir, "missing")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := normalizePath(tt.in); got != tt.want {
+				t.Errorf("normalizePath(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDuplicatesAndNesting(t *testing.T) {
+	dir := t.TempDir()
+	api := filepath.Join(dir, "api")
+	writeTree(t, dir, map[string]string{"api/web/go.mod": "", "blog/go.mod": ""})
+	if err := os.Symlink(api, filepath.Join(dir, "api-link")); err != nil {
+		t.Fatal(err)
+	}
+
+	old := Project{Name: "api", Path: api, CreatedAt: t0}
+	newer := Project{Name: "api-again", Path: filepath.Join(dir, "api-link"), CreatedAt: t1}
+	trashed := Project{Name: "api-trashed", Path: api + "/", CreatedAt: t2}
+	trashed.DeletedAt = &t2
+	web := Project{Name: "web", Path: filepath.Join(api, "web"), CreatedAt: t2}
+	blog := Project{Name: "blog", Path: filepath.Join(dir, "blog"), CreatedAt: t2}
+	projects := []Project{newer, blog, old, trashed, web}
+
+	groups := duplicateGroups(projects)
+	if len(groups) != 1 || !slices.Equal(groups[0], []int{2, 0}) {
+		t.Errorf("duplicateGroups() = %v, want [[2 0]] (oldest first, trash left out)", groups)
+	}
+
+	norm := normalizedPaths(projects)
+	tests := []struct {
+		path string
+		want int
+	}{
+		{filepath.Join(api, "web"), 0},
+		{filepath.Join(api, "web", "src"), 4},
+		{api, -1},
+		{filepath.Join(dir, "api2"), -1},
+		{dir, -1},
+	}
+	for _, tt := range tests {
+		if got := nestedParent(norm, tt.path); got != tt.want {
+			t.Errorf("nestedParent(%s) = %d, want %d", tt.path, got, tt.want)
+		}
+	}
+
+	m := model{projects: projects}
+	m.checkHealth()
+	if !m.duplicate[old.Path] || !m.duplicate[newer.Path] || m.duplicate[blog.Path] {
+		t.Errorf("duplicate = %v", m.duplicate)
+	}
+	if m.nested[web.Path] == "" || len(m.nested) != 1 {
+		t.Errorf("nested = %v, want only web", m.nested)
+	}
+	if got := m.findByPath(api+"/.", 2); got != 0 {
+		t.Errorf("findByPath() skipping the original = %d, want 0", got)
+	}
+}
+
+func TestMergeDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	keep := Project{Name: "api", Path: dir, Tags: []string{"go"}, CreatedAt: t0, OpenCount: 1, Opens: []time.Time{t1}}
+	other := Project{Name: "api2", Path: dir + "/", Tags: []string{"Go", "web"}, Description: "filled in",
+		Remote: "git@x:y", CreatedAt: t1, OpenCount: 2, Opens: []time.Time{t0, t2}, Pinned: 2}
+	pinned := testProject("pinned", t2)
+	pinned.Pinned = 1
+	m := storeWith(t, pinned, keep, other)
+
+	status, err := m.mergeSelectedDuplicates(projectKey(keep))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status == "" || len(m.projects) != 2 {
+		t.Fatalf("after merging: %q, %d projects; want 2", status, len(m.projects))
+	}
+	got := m.projects[indexOfName(t, &m, "api")]
+	if !slices.Equal(got.Tags, []string{"go", "web"}) || got.Description != "filled in" || got.Remote != "git@x:y" {
+		t.Errorf("merged metadata %+v", got)
+	}
+	if got.OpenCount != 3 || !slices.EqualFunc(got.Opens, []time.Time{t0, t1, t2}, time.Time.Equal) {
+		t.Errorf("merged opens %d %v", got.OpenCount, got.Opens)
+	}
+	if got.Pinned != 2 {
+		t.Errorf("merged pin %d, want 2 after pinned", got.Pinned)
+	}
+	if _, err := m.mergeSelectedDuplicates(projectKey(pinned)); err == nil {
+		t.Error("merging a project without duplicates succeeded")
+	}
+}
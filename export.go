@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// exportFormats lists the formats export can write, with their file extension.
+var exportFormats = []struct {
+	name string
+	ext  string
+}{
+	{"csv", ".csv"},
+	{"yaml", ".yaml"},
+	{"toml", ".toml"},
+	{"markdown", ".md"},
+	{"json", ".json"},
+}
+
+// formatExt returns the file extension for format, or "" if it is unknown.
+func formatExt(format string) string {
+	for _, f := range exportFormats {
+		if f.name == format {
+			return f.ext
+		}
+	}
+	return ""
+}
+
+// formatFromExt guesses the format of a file from its extension.
+func formatFromExt(path string) string {
+	lower := strings.ToLower(path)
+	for _, f := range exportFormats {
+		if strings.HasSuffix(lower, f.ext) {
+			return f.name
+		}
+	}
+	if strings.HasSuffix(lower, ".yml") {
+		return "yaml"
+	}
+	return ""
+}
+
+// exportRecord is a Project as written to YAML and TOML. It mirrors the JSON
+// field names so that every format reads the same.
+type exportRecord struct {
+	ID          string      `yaml:"id,omitempty" toml:"id,omitempty"`
+	Name        string      `yaml:"name" toml:"name"`
+	Path        string      `yaml:"path" toml:"path"`
+	Tags        []string    `yaml:"tags,omitempty" toml:"tags,omitempty"`
+	Description string      `yaml:"description,omitempty" toml:"description,omitempty"`
+	Opener      string      `yaml:"opener,omitempty" toml:"opener,omitempty"`
+	Remote      string      `yaml:"remote,omitempty" toml:"remote,omitempty"`
+	CreatedAt   time.Time   `yaml:"created_at" toml:"created_at"`
+	UpdatedAt   time.Time   `yaml:"updated_at" toml:"updated_at"`
+	OpenCount   int         `yaml:"open_count,omitempty" toml:"open_count,omitzero"`
+	Opens       []time.Time `yaml:"opens,omitempty" toml:"opens,omitempty"`
+	Pinned      int         `yaml:"pinned,omitempty" toml:"pinned,omitzero"`
+	DeletedAt   *time.Time  `yaml:"deleted_at,omitempty" toml:"deleted_at,omitempty"`
+	Book        string      `yaml:"-" toml:"-"`
+	Shared      string      `yaml:"-" toml:"-"`
+}
+
+func toRecord(p Project) exportRecord {
+	return exportRecord(p)
+}
+
+func fromRecord(r exportRecord) Project {
+	return Project(r)
+}
+
+// exportKeys maps the keys offered by the TUI export prompt to formats.
+var exportKeys = map[string]string{
+	"c": "csv",
+	"y": "yaml",
+	"t": "toml",
+	"m": "markdown",
+	"j": "json",
+}
+
+// exportFiltered writes the projects currently in the list to a timestamped
+// file in the working directory and returns the status message to show.
+func (m *model) exportFiltered(format string) (string, error) {
+	ps := make([]Project, len(m.filteredIdxs))
+	for i, idx := range m.filteredIdxs {
+		ps[i] = m.projects[idx]
+	}
+	var buf bytes.Buffer
+	if err := exportProjects(&buf, ps, format); err != nil {
+		return "", err
+	}
+	name := "phonebook-" + time.Now().Format("20060102-150405") + formatExt(format)
+	if err := os.WriteFile(name, buf.Bytes(), 0o644); err != nil {
+		return "", err
+	}
+	if abs, err := filepath.Abs(name); err == nil {
+		name = abs
+	}
+	return fmt.Sprintf("✓ Exported %d projects to %s", len(ps), name), nil
+}
+
+// tomlDocument is the top level of a TOML export; TOML has no top-level arrays.
+type tomlDocument struct {
+	Projects []exportRecord `toml:"projects"`
+}
+
+// csvHeader is the column order of CSV exports.
+var csvHeader = []string{
+	"id", "name", "path", "tags", "description", "opener", "remote",
+	"created_at", "updated_at", "open_count", "opens", "pinned", "deleted_at",
+}
+
+// exportProjects writes ps to w in format.
+func exportProjects(w io.Writer, ps []Project, format string) error {
+	switch format {
+	case "json":
+		return writeJSON(w, ps)
+	case "yaml":
+		records := make([]exportRecord, len(ps))
+		for i, p := range ps {
+			records[i] = toRecord(p)
+		}
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(records); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "toml":
+		doc := tomlDocument{Projects: make([]exportRecord, len(ps))}
+		for i, p := range ps {
+			doc.Projects[i] = toRecord(p)
+		}
+		return toml.NewEncoder(w).Encode(doc)
+	case "csv":
+		return exportCSV(w, ps)
+	case "markdown":
+		return exportMarkdown(w, ps)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func exportCSV(w io.Writer, ps []Project) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, p := range ps {
+		opens := make([]string, len(p.Opens))
+		for i, t := range p.Opens {
+			opens[i] = formatTime(t)
+		}
+		deleted := ""
+		if p.DeletedAt != nil {
+			deleted = formatTime(*p.DeletedAt)
+		}
+		err := cw.Write([]string{
+			p.ID, p.Name, p.Path, strings.Join(p.Tags, ","), p.Description, p.Opener, p.Remote,
+			formatTime(p.CreatedAt), formatTime(p.UpdatedAt), strconv.Itoa(p.OpenCount),
+			strings.Join(opens, " "), strconv.Itoa(p.Pinned), deleted,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// exportMarkdown writes a table meant for wikis and READMEs. It is not read
+// back by import.
+func exportMarkdown(w io.Writer, ps []Project) error {
+	cell := func(s string) string {
+		s = strings.ReplaceAll(s, "|", `\|`)
+		return strings.ReplaceAll(s, "\n", " ")
+	}
+	date := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format("2006-01-02")
+	}
+
+	var b strings.Builder
+	b.WriteString("| Name | Path | Tags | Description | Opener | Remote | Created | Updated | Opened | Pinned | Deleted |\n")
+	b.WriteString("|------|------|------|-------------|--------|--------|---------|---------|--------|--------|---------|\n")
+	for _, p := range ps {
+		tags := make([]string, len(p.Tags))
+		for i, t := range p.Tags {
+			tags[i] = "`" + cell(t) + "`"
+		}
+		opened := ""
+		if p.OpenCount > 0 {
+			opened = fmt.Sprintf("%d× (last %s)", p.OpenCount, date(p.lastOpened()))
+		}
+		pinned := ""
+		if p.Pinned > 0 {
+			pinned = strconv.Itoa(p.Pinned)
+		}
+		deleted := ""
+		if p.DeletedAt != nil {
+			deleted = date(*p.DeletedAt)
+		}
+		fmt.Fprintf(&b, "| %s | `%s` | %s | %s | %s | %s | %s | %s | %s | %s | %s |\n",
+			cell(p.Name), cell(p.Path), strings.Join(tags, " "), cell(p.Description),
+			cell(p.Opener), cell(p.Remote), date(p.CreatedAt), date(p.UpdatedAt),
+			opened, pinned, deleted)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// parseExport reads projects written by exportProjects. Markdown is
+// write-only.
+func parseExport(data []byte, format string) ([]Project, error) {
+	switch format {
+	case "json":
+		return parseProjects(data)
+	case "yaml":
+		var records []exportRecord
+		if err := yaml.Unmarshal(data, &records); err != nil {
+			return nil, err
+		}
+		ps := make([]Project, len(records))
+		for i, r := range records {
+			ps[i] = fromRecord(r)
+		}
+		return ps, nil
+	case "toml":
+		var doc tomlDocument
+		if _, err := toml.Decode(string(data), &doc); err != nil {
+			return nil, err
+		}
+		ps := make([]Project, len(doc.Projects))
+		for i, r := range doc.Projects {
+			ps[i] = fromRecord(r)
+		}
+		return ps, nil
+	case "csv":
+		return parseCSV(data)
+	case "markdown":
+		return nil, fmt.Errorf("markdown exports can't be imported; use csv, yaml, toml or json")
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// parseCSV reads a CSV export. Columns are matched by header name, so files
+// edited in a spreadsheet may reorder or drop them; only name and path are
+// required.
+func parseCSV(data []byte) ([]Project, error) {
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	col := make(map[string]int)
+	for i, h := range rows[0] {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, required := range []string{"name", "path"} {
+		if _, ok := col[required]; !ok {
+			return nil, fmt.Errorf("missing %q column", required)
+		}
+	}
+
+	var ps []Project
+	for n, row := range rows[1:] {
+		get := func(name string) string {
+			if i, ok := col[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		parseTime := func(name, s string) (time.Time, error) {
+			if s == "" {
+				return time.Time{}, nil
+			}
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return t, fmt.Errorf("row %d: %s: %w", n+2, name, err)
+			}
+			return t, nil
+		}
+
+		p := Project{
+			ID:          get("id"),
+			Name:        get("name"),
+			Path:        get("path"),
+			Tags:        splitTags(get("tags")),
+			Description: get("description"),
+			Opener:      get("opener"),
+			Remote:      get("remote"),
+		}
+		if p.CreatedAt, err = parseTime("created_at", get("created_at")); err != nil {
+			return nil, err
+		}
+		if p.UpdatedAt, err = parseTime("updated_at", get("updated_at")); err != nil {
+			return nil, err
+		}
+		for _, s := range strings.Fields(get("opens")) {
+			t, err := parseTime("opens", s)
+			if err != nil {
+				return nil, err
+			}
+			p.Opens = append(p.Opens, t)
+		}
+		if s := get("open_count"); s != "" {
+			if p.OpenCount, err = strconv.Atoi(s); err != nil {
+				return nil, fmt.Errorf("row %d: open_count: %w", n+2, err)
+			}
+		}
+		if s := get("pinned"); s != "" {
+			if p.Pinned, err = strconv.Atoi(s); err != nil {
+				return nil, fmt.Errorf("row %d: pinned: %w", n+2, err)
+			}
+		}
+		if s := get("deleted_at"); s != "" {
+			t, err := parseTime("deleted_at", s)
+			if err != nil {
+				return nil, err
+			}
+			p.DeletedAt = &t
+		}
+		ps = append(ps, p)
+	}
+	return ps, nil
+}
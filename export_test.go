@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestExportRoundTrip(t *testing.T) {
+	deleted := t2.Add(time.Nanosecond)
+	full := Project{
+		ID:          "01HK153X00AN87Z7RPYW0AG9J5",
+		Name:        "api",
+		Path:        "/src/my api",
+		Tags:        []string{"go", "web"},
+		Description: "Says \"hi\", then\nstops",
+		Opener:      `code --wait "{path}" &`,
+		Remote:      "git@github.com:me/api.git",
+		CreatedAt:   t0,
+		UpdatedAt:   t1.Add(123 * time.Millisecond),
+		OpenCount:   3,
+		Opens:       []time.Time{t1, t2},
+		Pinned:      2,
+		DeletedAt:   &deleted,
+	}
+	// JSON imports give projects without an ID one, so every project has one.
+	tests := []struct {
+		name     string
+		projects []Project
+	}{
+		{"every field", []Project{full}},
+		{"name and path only", []Project{{ID: "B", Name: "blog", Path: "/src/blog"}}},
+		{"commas and quotes", []Project{{ID: "C", Name: `a, "b"`, Path: "/src/a,b", Tags: []string{"x"}}}},
+		{"several", []Project{full, {ID: "B", Name: "blog", Path: "/src/blog", CreatedAt: t2}}},
+	}
+	for _, format := range []string{"csv", "yaml", "toml", "json"} {
+		for _, tt := range tests {
+			t.Run(format+"/"+tt.name, func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := exportProjects(&buf, tt.projects, format); err != nil {
+					t.Fatal(err)
+				}
+				got, err := parseExport(buf.Bytes(), format)
+				if err != nil {
+					t.Fatalf("parsing %s:\n%s\nerror: %v", format, buf.String(), err)
+				}
+				if len(got) != len(tt.projects) {
+					t.Fatalf("got %d projects, want %d", len(got), len(tt.projects))
+				}
+				for i := range got {
+					if !sameProject(got[i], tt.projects[i]) {
+						t.Errorf("project %d = %+v, want %+v", i, got[i], tt.projects[i])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		wantNames []string
+		check     func(Project) bool
+		wantErr   bool
+	}{
+		{"empty", "", nil, nil, false},
+		{"header only", "name,path\n", nil, nil, false},
+		{
+			"columns in any order and case",
+			"Path, NAME ,tags\n/src/api,api,\"go, web\"\n",
+			[]string{"api"},
+			func(p Project) bool { return p.Path == "/src/api" && slices.Equal(p.Tags, []string{"go", "web"}) },
+			false,
+		},
+		{
+			"unknown columns are ignored",
+			"name,path,stars\napi,/src/api,5\n",
+			[]string{"api"},
+			nil,
+			false,
+		},
+		{
+			"times",
+			"name,path,created_at,opens\napi,/src/api,2024-01-01T12:00:00Z,2024-01-01T13:00:00Z 2024-01-01T14:00:00Z\n",
+			[]string{"api"},
+			func(p Project) bool {
+				return p.CreatedAt.Equal(t0) && slices.EqualFunc(p.Opens, []time.Time{t1, t2}, time.Time.Equal)
+			},
+			false,
+		},
+		{"missing path column", "name\napi\n", nil, nil, true},
+		{"header in another separator", "name;path\napi;/src/api\n", nil, nil, true},
+		{"unbalanced quote in the header", "\"name,path\napi,/src/api\n", nil, nil, true},
+		{"short row", "name,path,tags\napi\n", nil, nil, true},
+		{"bad time", "name,path,created_at\napi,/src/api,yesterday\n", nil, nil, true},
+		{"bad count", "name,path,open_count\napi,/src/api,many\n", nil, nil, true},
+		{"bad pin", "name,path,pinned\napi,/src/api,top\n", nil, nil, true},
+		{"unbalanced quote", "name,path\n\"api,/src/api\n", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCSV([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCSV() error = %v, want error %v", err, tt.wantErr)
+			}
+			var names []string
+			for _, p := range got {
+				names = append(names, p.Name)
+				if tt.check != nil && !tt.check(p) {
+					t.Errorf("parseCSV() = %+v", p)
+				}
+			}
+			if !slices.Equal(names, tt.wantNames) {
+				t.Errorf("parseCSV() names = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
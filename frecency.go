@@ -0,0 +1,69 @@
+package main
+
+import "time"
+
+// maxRecordedOpens caps how many open timestamps are kept per project.
+// OpenCount keeps counting past it.
+const maxRecordedOpens = 10
+
+// recordOpen notes that p was opened at t.
+func (p *Project) recordOpen(t time.Time) {
+	p.OpenCount++
+	p.Opens = append(p.Opens, t)
+	if len(p.Opens) > maxRecordedOpens {
+		p.Opens = p.Opens[len(p.Opens)-maxRecordedOpens:]
+	}
+}
+
+// lastOpened returns when p was last opened, or the zero time.
+func (p Project) lastOpened() time.Time {
+	if len(p.Opens) == 0 {
+		return time.Time{}
+	}
+	return p.Opens[len(p.Opens)-1]
+}
+
+// openWeight buckets the age of an open the way zoxide does: recent opens
+// count for more than old ones.
+func openWeight(age time.Duration) float64 {
+	switch {
+	case age < time.Hour:
+		return 4
+	case age < 24*time.Hour:
+		return 2
+	case age < 7*24*time.Hour:
+		return 0.5
+	default:
+		return 0.25
+	}
+}
+
+// frecency combines how often and how recently p was opened. The weights of
+// the recorded opens are averaged and scaled by the total open count, so a
+// single open doesn't outrank a project used every day.
+func frecency(p Project, now time.Time) float64 {
+	if p.OpenCount == 0 || len(p.Opens) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, t := range p.Opens {
+		sum += openWeight(now.Sub(t))
+	}
+	return sum / float64(len(p.Opens)) * float64(p.OpenCount)
+}
+
+// rankedBefore puts pinned projects first in pin order, then orders by
+// frecency and finally by last metadata change.
+func rankedBefore(a, b Project, now time.Time) bool {
+	if (a.Pinned > 0) != (b.Pinned > 0) {
+		return a.Pinned > 0
+	}
+	if a.Pinned != b.Pinned {
+		return a.Pinned < b.Pinned
+	}
+	fa, fb := frecency(a, now), frecency(b, now)
+	if fa != fb {
+		return fa > fb
+	}
+	return a.UpdatedAt.After(b.UpdatedAt)
+}
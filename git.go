@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// gitCacheTTL is how long a git status stays fresh in the detail pane.
+	gitCacheTTL = 30 * time.Second
+	// gitTimeout bounds each git invocation so a slow repo can't pile up work.
+	gitTimeout = 5 * time.Second
+)
+
+type gitRemote struct {
+	name string
+	url  string
+}
+
+// gitInfo is a snapshot of a repository's state for the detail pane.
+type gitInfo struct {
+	isRepo     bool
+	branch     string
+	upstream   string
+	ahead      int
+	behind     int
+	staged     int
+	modified   int
+	untracked  int
+	conflicted int
+
+	lastSubject string
+	lastAuthor  string
+	lastTime    time.Time
+
+	remotes   []gitRemote
+	err       error
+	fetchedAt time.Time
+}
+
+func (g gitInfo) dirty() bool {
+	return g.staged+g.modified+g.untracked+g.conflicted > 0
+}
+
+// gitStatusMsg carries the result of fetchGitInfo back to Update.
+type gitStatusMsg struct {
+	path string
+	info gitInfo
+}
+
+func gitStatusCmd(path string) tea.Cmd {
+	return func() tea.Msg {
+		return gitStatusMsg{path: path, info: fetchGitInfo(path)}
+	}
+}
+
+// runGit runs git in dir and returns its stdout.
+func runGit(dir string, args ...string) (string, error) {
+	return runGitTimeout(gitTimeout, dir, args...)
+}
+
+// runGitTimeout is runGit with a different time limit, for commands that
+// talk to a remote.
+func runGitTimeout(timeout time.Duration, dir string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	c := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	// Don't take the index lock just to look at it.
+	c.Env = append(os.Environ(), "GIT_OPTIONAL_LOCKS=0")
+	var stdout, stderr bytes.Buffer
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s", msg)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+// fetchGitInfo collects branch, working tree, last commit and remote details
+// for the repository at path.
+func fetchGitInfo(path string) gitInfo {
+	info := gitInfo{fetchedAt: time.Now()}
+
+	status, err := runGit(path, "status", "--porcelain=v2", "--branch")
+	if err != nil {
+		if !strings.Contains(err.Error(), "not a git repository") {
+			info.err = err
+		}
+		return info
+	}
+	info.isRepo = true
+	parseGitStatus(status, &info)
+
+	if out, err := runGit(path, "log", "-1", "--format=%s%x1f%an%x1f%ct"); err == nil {
+		parts := strings.Split(strings.TrimSpace(out), "\x1f")
+		if len(parts) == 3 {
+			info.lastSubject = parts[0]
+			info.lastAuthor = parts[1]
+			if ts, err := strconv.ParseInt(parts[2], 10, 64); err == nil {
+				info.lastTime = time.Unix(ts, 0)
+			}
+		}
+	}
+
+	if out, err := runGit(path, "remote", "-v"); err == nil {
+		info.remotes = parseGitRemotes(out)
+	}
+	return info
+}
+
+// parseGitStatus reads `git status --porcelain=v2 --branch` output into info.
+func parseGitStatus(out string, info *gitInfo) {
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "# branch.head "):
+			info.branch = strings.TrimPrefix(line, "# branch.head ")
+		case strings.HasPrefix(line, "# branch.upstream "):
+			info.upstream = strings.TrimPrefix(line, "# branch.upstream ")
+		case strings.HasPrefix(line, "# branch.ab "):
+			fmt.Sscanf(strings.TrimPrefix(line, "# branch.ab "), "+%d -%d", &info.ahead, &info.behind)
+		case strings.HasPrefix(line, "1 "), strings.HasPrefix(line, "2 "):
+			if len(line) < 4 {
+				continue
+			}
+			if line[2] != '.' {
+				info.staged++
+			}
+			if line[3] != '.' {
+				info.modified++
+			}
+		case strings.HasPrefix(line, "u "):
+			info.conflicted++
+		case strings.HasPrefix(line, "? "):
+			info.untracked++
+		}
+	}
+}
+
+// parseGitRemotes reads `git remote -v` output, keeping the fetch URLs.
+func parseGitRemotes(out string) []gitRemote {
+	var remotes []gitRemote
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[2] == "(fetch)" {
+			remotes = append(remotes, gitRemote{name: fields[0], url: fields[1]})
+		}
+	}
+	return remotes
+}
+
+// humanizeAge formats a duration as a short "3 days ago" style string.
+func humanizeAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d.Minutes()), "minute") + " ago"
+	case d < 24*time.Hour:
+		return plural(int(d.Hours()), "hour") + " ago"
+	case d < 30*24*time.Hour:
+		return plural(int(d.Hours()/24), "day") + " ago"
+	case d < 365*24*time.Hour:
+		return plural(int(d.Hours()/24/30), "month") + " ago"
+	default:
+		return plural(int(d.Hours()/24/365), "year") + " ago"
+	}
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// renderGitInfo renders the git section of the detail pane.
+func renderGitInfo(info gitInfo) string {
+	muted := lipgloss.NewStyle().Foreground(mutedColor)
+	if info.err != nil {
+		return errorStyle.UnsetMarginLeft().Render("git: "+info.err.Error()) + "\n"
+	}
+
+	var b strings.Builder
+
+	branch := info.branch
+	if branch == "(detached)" {
+		branch = "detached HEAD"
+	}
+	line := tagStyle.Render(" " + branch)
+	if info.upstream != "" {
+		line += muted.Render(" → " + info.upstream)
+		if info.ahead > 0 {
+			line += statusStyle.UnsetMarginLeft().Render(fmt.Sprintf(" ↑%d", info.ahead))
+		}
+		if info.behind > 0 {
+			line += warningStyle.Render(fmt.Sprintf(" ↓%d", info.behind))
+		}
+	}
+	b.WriteString(line + "\n")
+
+	if info.dirty() {
+		var parts []string
+		if info.staged > 0 {
+			parts = append(parts, fmt.Sprintf("%d staged", info.staged))
+		}
+		if info.modified > 0 {
+			parts = append(parts, fmt.Sprintf("%d modified", info.modified))
+		}
+		if info.untracked > 0 {
+			parts = append(parts, fmt.Sprintf("%d untracked", info.untracked))
+		}
+		if info.conflicted > 0 {
+			parts = append(parts, fmt.Sprintf("%d conflicted", info.conflicted))
+		}
+		b.WriteString(warningStyle.Render("● dirty: "+strings.Join(parts, ", ")) + "\n")
+	} else {
+		b.WriteString(statusStyle.UnsetMarginLeft().Render("✓ clean") + "\n")
+	}
+
+	if info.lastSubject != "" {
+		b.WriteString(detailValueStyle.Render(info.lastSubject) + "\n")
+		b.WriteString(muted.Render(fmt.Sprintf("  %s, %s", info.lastAuthor, humanizeAge(time.Since(info.lastTime)))) + "\n")
+	}
+
+	for _, r := range info.remotes {
+		b.WriteString(muted.Render(fmt.Sprintf("%s  %s", r.name, r.url)) + "\n")
+	}
+	return b.String()
+}
+
+// refreshGitStatus starts a git status fetch for the selected project if its
+// cached status is missing or stale and no fetch is already running.
+func (m model) refreshGitStatus() tea.Cmd {
+	if m.mode != viewList || len(m.filteredIdxs) == 0 {
+		return nil
+	}
+	path := m.projects[m.filteredIdxs[m.cursor]].Path
+	if m.gitPending[path] || m.missing[path] {
+		return nil
+	}
+	if info, ok := m.gitCache[path]; ok && time.Since(info.fetchedAt) < gitCacheTTL {
+		return nil
+	}
+	m.gitPending[path] = true
+	return gitStatusCmd(path)
+}
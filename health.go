@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// pathMissing reports whether a project's directory is gone.
+func pathMissing(p Project) bool {
+	info, err := os.Stat(p.Path)
+	return err != nil || !info.IsDir()
+}
+
+// checkHealth refreshes the per-path warnings shown in the list: missing
+// directories, directories registered more than once, and projects nested
+// inside another project.
+func (m *model) checkHealth() {
+	m.missing = make(map[string]bool)
+	m.duplicate = make(map[string]bool)
+	m.nested = make(map[string]string)
+
+	norm := normalizedPaths(m.projects)
+	seen := make(map[string]string, len(norm))
+	for i, p := range m.projects {
+		if norm[i] == "" {
+			continue
+		}
+		if pathMissing(p) {
+			m.missing[p.Path] = true
+		}
+		if m.allBooks {
+			// The same directory may well be listed in several books.
+			continue
+		}
+		if first, ok := seen[norm[i]]; ok {
+			m.duplicate[first] = true
+			m.duplicate[p.Path] = true
+		} else {
+			seen[norm[i]] = p.Path
+		}
+		if parent := nestedParent(norm, norm[i]); parent >= 0 {
+			m.nested[p.Path] = m.projects[parent].Name
+		}
+	}
+}
+
+// primaryRemote picks the remote URL that identifies a repository: origin if
+// present, otherwise the first one.
+func primaryRemote(remotes []gitRemote) string {
+	for _, r := range remotes {
+		if r.name == "origin" {
+			return r.url
+		}
+	}
+	if len(remotes) > 0 {
+		return remotes[0].url
+	}
+	return ""
+}
+
+// normalizeRemote reduces a git URL to host/owner/repo so that the ssh and
+// https forms of the same remote compare equal.
+func normalizeRemote(url string) string {
+	u := strings.TrimSpace(strings.ToLower(url))
+	u = strings.TrimSuffix(strings.TrimSuffix(u, "/"), ".git")
+	if i := strings.Index(u, "://"); i >= 0 {
+		u = u[i+3:]
+	} else if at := strings.Index(u, "@"); at >= 0 && strings.Contains(u[at:], ":") {
+		// scp-like syntax: git@host:owner/repo
+		u = strings.Replace(u, ":", "/", 1)
+	}
+	if at := strings.Index(u, "@"); at >= 0 {
+		u = u[at+1:]
+	}
+	return u
+}
+
+// repoRemote returns the primary remote of the repository at dir, or "".
+func repoRemote(dir string) string {
+	out, err := runGit(dir, "remote", "-v")
+	if err != nil {
+		return ""
+	}
+	return primaryRemote(parseGitRemotes(out))
+}
+
+// recordRemote remembers the git remote of the project at path so it can be
+// found again after a move. It is bookkeeping: UpdatedAt is left alone, and
+// the remote is only kept in memory until the next save writes it, so
+// browsing the list never rewrites the store.
+func (m *model) recordRemote(path, remote string) {
+	if remote == "" {
+		return
+	}
+	for i := range m.projects {
+		if m.projects[i].Path == path {
+			m.projects[i].Remote = remote
+		}
+	}
+}
+
+// relocation is a suggested new path for a project whose directory is missing.
+type relocation struct {
+	path   string
+	reason string
+	score  int
+}
+
+// findRelocations searches roots for directories that look like the moved
+// project: the same git remote is a strong match, the same directory name or
+// project name a weaker one. The best suggestions come first.
+func findRelocations(p Project, roots []string, depth int) ([]relocation, error) {
+	candidates, err := scanForProjects(roots, depth, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	wantRemote := normalizeRemote(p.Remote)
+	oldBase := strings.ToLower(filepath.Base(p.Path))
+	name := strings.ToLower(p.Name)
+
+	var found []relocation
+	for _, c := range candidates {
+		path := c.project.Path
+		base := strings.ToLower(filepath.Base(path))
+		switch {
+		case wantRemote != "" && normalizeRemote(repoRemote(path)) == wantRemote:
+			found = append(found, relocation{path: path, reason: "same git remote", score: 3})
+		case base == oldBase:
+			found = append(found, relocation{path: path, reason: "same directory name", score: 2})
+		case base == name:
+			found = append(found, relocation{path: path, reason: "same name as project", score: 1})
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].score > found[j].score
+	})
+	return found, nil
+}
+
+// bestRelocation returns the top suggestion if it is unambiguous.
+func bestRelocation(found []relocation) (relocation, bool) {
+	if len(found) == 0 {
+		return relocation{}, false
+	}
+	if len(found) > 1 && found[1].score == found[0].score {
+		return relocation{}, false
+	}
+	return found[0], true
+}
+
+// relocateMsg carries relocation suggestions for the project with key.
+type relocateMsg struct {
+	key   string
+	found []relocation
+	err   error
+}
+
+func (m model) relocateCmd(p Project) tea.Cmd {
+	roots, depth := m.config.scanRoots(), m.config.scanDepth()
+	key := projectKey(p)
+	return func() tea.Msg {
+		found, err := findRelocations(p, roots, depth)
+		return relocateMsg{key: key, found: found, err: err}
+	}
+}
+
+// relocateProject points the project with key at a new path.
+func (m *model) relocateProject(key, path string) (string, error) {
+	for i, p := range m.projects {
+		if projectKey(p) != key {
+			continue
+		}
+		m.checkpoint(fmt.Sprintf("relocate '%s'", p.Name))
+		p.Path = path
+		if remote := repoRemote(path); remote != "" {
+			p.Remote = remote
+		}
+		if err := m.updateProject(i, p); err != nil {
+			m.dropCheckpoint()
+			return "", err
+		}
+		return fmt.Sprintf("✓ Relocated '%s' to %s", p.Name, path), nil
+	}
+	return "", fmt.Errorf("project no longer exists")
+}
+
+// handleRelocate turns relocation results into a confirmation prompt.
+func (m *model) handleRelocate(msg relocateMsg) {
+	if msg.err != nil {
+		m.statusMessage = fmt.Sprintf("Relocate failed: %v", msg.err)
+		m.isError = true
+		return
+	}
+	if len(msg.found) == 0 {
+		m.statusMessage = "No likely new location found under the scan roots"
+		m.isError = true
+		return
+	}
+
+	best := msg.found[0]
+	question := fmt.Sprintf("Relocate to %s (%s)?", best.path, best.reason)
+	if len(msg.found) > 1 {
+		question = fmt.Sprintf("Relocate to %s (%s, 1 of %d matches)?", best.path, best.reason, len(msg.found))
+	}
+	key := msg.key
+	m.confirm = &confirmPrompt{
+		question: question,
+		onYes: func(m *model) (string, error) {
+			return m.relocateProject(key, best.path)
+		},
+	}
+}
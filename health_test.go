@@ -0,0 +1,156 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeRemote(t *testing.T) {
+	tests := []struct {
+		url, want string
+	}{
+		{"git@github.com:me/api.git", "github.com/me/api"},
+		{"https://github.com/me/api", "github.com/me/api"},
+		{"https://GitHub.com/Me/API.git/", "github.com/me/api"},
+		{"ssh://git@gitlab.example.com:2222/team/api.git", "gitlab.example.com:2222/team/api"},
+		{"https://user@bitbucket.org/me/api.git", "bitbucket.org/me/api"},
+		{"/srv/git/api.git", "/srv/git/api"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := normalizeRemote(tt.url); got != tt.want {
+				t.Errorf("normalizeRemote(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrimaryRemote(t *testing.T) {
+	tests := []struct {
+		name    string
+		remotes []gitRemote
+		want    string
+	}{
+		{"none", nil, ""},
+		{"origin wins", []gitRemote{{"upstream", "u"}, {"origin", "o"}}, "o"},
+		{"first otherwise", []gitRemote{{"upstream", "u"}, {"fork", "f"}}, "u"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := primaryRemote(tt.remotes); got != tt.want {
+				t.Errorf("primaryRemote() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindRelocations(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"work/api/go.mod":       "",
+		"archive/api/go.mod":    "",
+		"work/phonebook/go.mod": "",
+		"work/other/go.mod":     "",
+	})
+	tests := []struct {
+		name     string
+		project  Project
+		want     []string
+		wantBest string
+	}{
+		{"same directory name, twice", Project{Name: "x", Path: "/gone/api"}, []string{"archive/api", "work/api"}, ""},
+		{"same name", Project{Name: "Phonebook", Path: "/gone/pb"}, []string{"work/phonebook"}, "work/phonebook"},
+		{"directory name beats project name", Project{Name: "other", Path: "/gone/phonebook"}, []string{"work/phonebook", "work/other"}, "work/phonebook"},
+		{"nothing alike", Project{Name: "z", Path: "/gone/z"}, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := findRelocations(tt.project, []string{root}, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range found {
+				rel, _ := filepath.Rel(root, r.path)
+				got = append(got, rel)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("found %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("found %v, want %v", got, tt.want)
+					break
+				}
+			}
+			best, ok := bestRelocation(found)
+			if rel, _ := filepath.Rel(root, best.path); ok != (tt.wantBest != "") || (ok && rel != tt.wantBest) {
+				t.Errorf("bestRelocation() = %q, %v; want %q", best.path, ok, tt.wantBest)
+			}
+		})
+	}
+}
+
+func TestCheckHealth(t *testing.T) {
+	dir := t.TempDir()
+	gone := filepath.Join(dir, "gone")
+	trashed := Project{Name: "old", Path: filepath.Join(dir, "old")}
+	trashed.DeletedAt = &t0
+	m := model{projects: []Project{
+		{Name: "here", Path: dir},
+		{Name: "gone", Path: gone},
+		trashed,
+	}}
+	m.checkHealth()
+	if !m.missing[gone] || len(m.missing) != 1 {
+		t.Errorf("missing = %v, want only %s", m.missing, gone)
+	}
+}
+
+func TestRelocateProject(t *testing.T) {
+	m := storeWith(t, testProject("api", t0))
+	key := projectKey(m.projects[0])
+	moved := t.TempDir()
+	if _, err := m.relocateProject(key, moved); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.projects[0].Path; got != moved {
+		t.Errorf("path = %q, want %q", got, moved)
+	}
+	if _, err := m.undo(); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.projects[0].Path; got != "/src/api" {
+		t.Errorf("path after undo = %q, want /src/api", got)
+	}
+	if _, err := m.relocateProject("no such key", moved); err == nil {
+		t.Error("relocating a missing project succeeded")
+	}
+}
+
+func TestRecordRemoteWaitsForSave(t *testing.T) {
+	m := storeWith(t, testProject("api", t0), testProject("blog", t1))
+	before, _ := os.ReadFile(m.projectsFile)
+
+	m.recordRemote("/src/api", "git@github.com:me/api.git")
+	if after, _ := os.ReadFile(m.projectsFile); string(after) != string(before) {
+		t.Error("recordRemote() rewrote the store")
+	}
+	if m.changedOnDisk() {
+		t.Error("changedOnDisk() = true after recordRemote()")
+	}
+
+	if err := m.togglePin(indexOfName(t, &m, "blog")); err != nil {
+		t.Fatal(err)
+	}
+	saved := model{projectsFile: m.projectsFile}
+	if err := saved.loadProjects(); err != nil {
+		t.Fatal(err)
+	}
+	api := saved.projects[indexOfName(t, &saved, "api")]
+	if api.Remote != "git@github.com:me/api.git" || !api.UpdatedAt.Equal(t0) {
+		t.Errorf("after the next save api has remote %q, updated %v; want the remote and no new update time", api.Remote, api.UpdatedAt)
+	}
+}
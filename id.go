@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"time"
+)
+
+// crockford is the base32 alphabet ULIDs are written in.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newID returns a ULID for a project created at t: 48 bits of milliseconds
+// followed by 80 random bits, so IDs sort by creation time.
+func newID(t time.Time) string {
+	var entropy [10]byte
+	rand.Read(entropy[:])
+	return ulid(t, entropy)
+}
+
+// stableID returns the ULID given to a project created at t by the version 2
+// migration. It is derived from t alone, so every machine that migrates its
+// own copy of a phonebook gives the project the same ID.
+func stableID(t time.Time) string {
+	sum := sha256.Sum256([]byte(t.UTC().Format(time.RFC3339Nano)))
+	var entropy [10]byte
+	copy(entropy[:], sum[:])
+	return ulid(t, entropy)
+}
+
+func ulid(t time.Time, entropy [10]byte) string {
+	var b [16]byte
+	ms := uint64(max(t.UnixMilli(), 0))
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> (40 - 8*i))
+	}
+	copy(b[6:], entropy[:])
+
+	var hi, lo uint64
+	for i := 0; i < 8; i++ {
+		hi = hi<<8 | uint64(b[i])
+		lo = lo<<8 | uint64(b[8+i])
+	}
+	// 26 characters of 5 bits cover 130 bits; the top two are always zero.
+	var out [26]byte
+	for i := range out {
+		n := uint(125 - 5*i)
+		var v uint64
+		switch {
+		case n == 0:
+			v = lo
+		case n < 64:
+			v = lo>>n | hi<<(64-n)
+		default:
+			v = hi >> (n - 64)
+		}
+		out[i] = crockford[v&31]
+	}
+	return string(out[:])
+}
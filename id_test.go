@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestULID(t *testing.T) {
+	var zero, ones [10]byte
+	for i := range ones {
+		ones[i] = 0xff
+	}
+	tests := []struct {
+		name    string
+		t       time.Time
+		entropy [10]byte
+		want    string
+	}{
+		{"spec example", time.UnixMilli(1469918176385), zero, "01ARYZ6S410000000000000000"},
+		{"entropy fills the end", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), ones, "01HK153X00ZZZZZZZZZZZZZZZZ"},
+		{"epoch", time.UnixMilli(0), zero, "00000000000000000000000000"},
+		{"before the epoch", time.Time{}, zero, "00000000000000000000000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ulid(tt.t, tt.entropy); got != tt.want {
+				t.Errorf("ulid() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStableID(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		a, b time.Time
+		same bool
+	}{
+		{"same time", created, created, true},
+		{"same instant in another zone", created, created.In(time.FixedZone("CET", 3600)), true},
+		{"a nanosecond apart", created, created.Add(time.Nanosecond), false},
+		{"a day apart", created, created.AddDate(0, 0, 1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := stableID(tt.a), stableID(tt.b)
+			if (a == b) != tt.same {
+				t.Errorf("stableID(%v) = %s, stableID(%v) = %s; same = %v, want %v", tt.a, a, tt.b, b, a == b, tt.same)
+			}
+			if !strings.HasPrefix(a, ulid(tt.a, [10]byte{})[:10]) {
+				t.Errorf("stableID(%v) = %s doesn't start with the time", tt.a, a)
+			}
+		})
+	}
+}
+
+func TestNewIDSortsByTime(t *testing.T) {
+	earlier, later := newID(t0), newID(t0.Add(time.Millisecond))
+	if earlier >= later {
+		t.Errorf("newID at %v = %s, not before %s a millisecond later", t0, earlier, later)
+	}
+}
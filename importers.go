@@ -0,0 +1,374 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// importEntry is a project found in another tool's data: a directory and,
+// when the tool has one, the name it used. Exports of our own carry the full
+// project instead.
+type importEntry struct {
+	name    string
+	path    string
+	project *Project
+}
+
+// importSource reads projects from another project manager or tool. arg is
+// the file (or directory) given on the command line, or "" to look in the
+// tool's usual places.
+type importSource struct {
+	name string
+	help string
+	read func(arg string) ([]importEntry, error)
+}
+
+// importSources lists the supported tools in the order they are documented.
+var importSources = []importSource{
+	{"vscode", "recently opened folders from VS Code's storage.json", readVSCode},
+	{"projectile", "Emacs projectile-bookmarks.eld", readProjectile},
+	{"zoxide", "zoxide database via `zoxide query -ls`, or a saved copy of its output", readZoxide},
+	{"tmuxinator", "root directories of tmuxinator project configs", readTmuxinator},
+	{"smug", "root directories of smug session configs", readSmug},
+	{"paths", "a file with one directory per line (- for stdin)", readPathList},
+	{"csv", "a phonebook CSV export", readExport("csv")},
+	{"yaml", "a phonebook YAML export", readExport("yaml")},
+	{"toml", "a phonebook TOML export", readExport("toml")},
+	{"json", "a phonebook JSON export or projects.json", readExport("json")},
+}
+
+func findImportSource(name string) (importSource, bool) {
+	for _, s := range importSources {
+		if s.name == name {
+			return s, true
+		}
+	}
+	return importSource{}, false
+}
+
+// firstExisting returns the first of paths that exists, or an error naming
+// all of them.
+func firstExisting(what string, paths []string) (string, error) {
+	for _, p := range paths {
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("no %s found (looked in %s); pass the file explicitly", what, strings.Join(paths, ", "))
+}
+
+func readVSCode(arg string) ([]importEntry, error) {
+	path := arg
+	if path == "" {
+		cfg, err := os.UserConfigDir()
+		if err != nil {
+			return nil, err
+		}
+		var candidates []string
+		for _, app := range []string{"Code", "Code - Insiders", "VSCodium"} {
+			candidates = append(candidates,
+				filepath.Join(cfg, app, "User", "globalStorage", "storage.json"),
+				filepath.Join(cfg, app, "storage.json"))
+		}
+		if path, err = firstExisting("VS Code storage.json", candidates); err != nil {
+			return nil, err
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	// The layout of storage.json has changed between VS Code releases, but
+	// every version stores folders as file:// URIs, so collect those wherever
+	// they appear.
+	var entries []importEntry
+	var walk func(v any)
+	add := func(s string) {
+		if !strings.HasPrefix(s, "file://") {
+			return
+		}
+		u, err := url.Parse(s)
+		if err != nil || strings.HasSuffix(u.Path, ".code-workspace") {
+			return
+		}
+		entries = append(entries, importEntry{path: u.Path})
+	}
+	walk = func(v any) {
+		switch v := v.(type) {
+		case string:
+			add(v)
+		case []any:
+			for _, e := range v {
+				walk(e)
+			}
+		case map[string]any:
+			for k, e := range v {
+				add(k)
+				walk(e)
+			}
+		}
+	}
+	walk(doc)
+	return entries, nil
+}
+
+// elispString matches a double-quoted Emacs Lisp string.
+var elispString = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"`)
+
+func readProjectile(arg string) ([]importEntry, error) {
+	path := arg
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path, err = firstExisting("projectile bookmarks", []string{
+			filepath.Join(home, ".emacs.d", "projectile-bookmarks.eld"),
+			filepath.Join(home, ".config", "emacs", "projectile-bookmarks.eld"),
+			filepath.Join(home, ".emacs.d", ".local", "cache", "projectile-bookmarks.eld"),
+			filepath.Join(home, ".config", "emacs", ".local", "cache", "projectile-bookmarks.eld"),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []importEntry
+	for _, m := range elispString.FindAllSubmatch(data, -1) {
+		if s, err := strconv.Unquote(`"` + string(m[1]) + `"`); err == nil {
+			entries = append(entries, importEntry{path: s})
+		}
+	}
+	return entries, nil
+}
+
+func readZoxide(arg string) ([]importEntry, error) {
+	var data []byte
+	var err error
+	if arg == "" {
+		if data, err = exec.Command("zoxide", "query", "--list", "--score").Output(); err != nil {
+			return nil, fmt.Errorf("running zoxide: %w", err)
+		}
+	} else if data, err = readArg(arg); err != nil {
+		return nil, err
+	}
+
+	// Lines are "<score> <path>"; plain paths are accepted too.
+	var entries []importEntry
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if score, rest, ok := strings.Cut(line, " "); ok {
+			if _, err := strconv.ParseFloat(score, 64); err == nil {
+				line = strings.TrimSpace(rest)
+			}
+		}
+		if line != "" {
+			entries = append(entries, importEntry{path: line})
+		}
+	}
+	return entries, sc.Err()
+}
+
+func readTmuxinator(arg string) ([]importEntry, error) {
+	dirs := []string{arg}
+	if arg == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		dirs = []string{filepath.Join(home, ".config", "tmuxinator"), filepath.Join(home, ".tmuxinator")}
+		if env := os.Getenv("TMUXINATOR_CONFIG"); env != "" {
+			dirs = append([]string{env}, dirs...)
+		}
+	}
+	return readSessionConfigs("tmuxinator", dirs, "name")
+}
+
+func readSmug(arg string) ([]importEntry, error) {
+	dirs := []string{arg}
+	if arg == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		dirs = []string{filepath.Join(home, ".config", "smug")}
+	}
+	return readSessionConfigs("smug", dirs, "session")
+}
+
+// readSessionConfigs reads the root directory and session name from every
+// YAML file in dirs (each of which may also be a single file). nameKey is the
+// top-level key holding the session name.
+func readSessionConfigs(tool string, dirs []string, nameKey string) ([]importEntry, error) {
+	var files []string
+	for _, dir := range dirs {
+		info, err := os.Stat(dir)
+		if err != nil {
+			continue
+		}
+		if !info.IsDir() {
+			files = append(files, dir)
+			continue
+		}
+		for _, ext := range []string{"*.yml", "*.yaml"} {
+			matches, _ := filepath.Glob(filepath.Join(dir, ext))
+			files = append(files, matches...)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no %s configs found (looked in %s); pass a file or directory explicitly", tool, strings.Join(dirs, ", "))
+	}
+
+	var entries []importEntry
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		root := yamlTopLevelValue(data, "root")
+		if root == "" {
+			continue
+		}
+		name := yamlTopLevelValue(data, nameKey)
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(f), filepath.Ext(f))
+		}
+		entries = append(entries, importEntry{name: name, path: root})
+	}
+	return entries, nil
+}
+
+// yamlTopLevelValue returns the scalar value of an unindented `key: value`
+// line. Like tomlStringValue, it only handles the simple fields we need.
+func yamlTopLevelValue(data []byte, key string) string {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" || line[0] == ' ' || line[0] == '\t' || line[0] == '#' {
+			continue
+		}
+		k, v, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(k) != key {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		if i := strings.Index(v, " #"); i >= 0 {
+			v = strings.TrimSpace(v[:i])
+		}
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			v = v[1 : len(v)-1]
+		}
+		return v
+	}
+	return ""
+}
+
+func readPathList(arg string) ([]importEntry, error) {
+	if arg == "" {
+		return nil, usageError("paths needs a file (- for stdin)")
+	}
+	data, err := readArg(arg)
+	if err != nil {
+		return nil, err
+	}
+	var entries []importEntry
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			entries = append(entries, importEntry{path: line})
+		}
+	}
+	return entries, sc.Err()
+}
+
+// readExport returns a reader for files written by `phonebook export`.
+func readExport(format string) func(arg string) ([]importEntry, error) {
+	return func(arg string) ([]importEntry, error) {
+		if arg == "" {
+			return nil, usageError("%s needs a file (- for stdin)", format)
+		}
+		data, err := readArg(arg)
+		if err != nil {
+			return nil, err
+		}
+		ps, err := parseExport(data, format)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", arg, err)
+		}
+		entries := make([]importEntry, len(ps))
+		for i := range ps {
+			entries[i] = importEntry{name: ps[i].Name, path: ps[i].Path, project: &ps[i]}
+		}
+		return entries, nil
+	}
+}
+
+// readArg reads a file, or stdin when arg is "-".
+func readArg(arg string) ([]byte, error) {
+	if arg == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(arg)
+}
+
+// importCandidates turns imported entries into a checklist like the one the
+// scanner produces. Entries already in the phonebook or listed twice are
+// dropped; directories that don't exist are kept but left unselected.
+func importCandidates(entries []importEntry, known map[string]bool) []scanCandidate {
+	var candidates []scanCandidate
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		path := normalizePath(e.path)
+		if known[path] || seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		info, err := os.Stat(path)
+		exists := err == nil && info.IsDir()
+		if e.project != nil {
+			// Our own exports already have everything; keep it as is.
+			p := *e.project
+			p.Path = path
+			c := scanCandidate{project: p, markers: []string{"directory not found"}}
+			if exists {
+				c.markers, c.selected = findMarkers(path), true
+			}
+			candidates = append(candidates, c)
+			continue
+		}
+		if !exists {
+			candidates = append(candidates, scanCandidate{
+				project: Project{Name: filepath.Base(path), Path: path},
+				markers: []string{"directory not found"},
+			})
+			continue
+		}
+		c := newScanCandidate(path, findMarkers(path))
+		if e.name != "" {
+			c.project.Name = e.name
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestImportSources(t *testing.T) {
+	tests := []struct {
+		source  string
+		files   map[string]string
+		arg     string // relative to the fixture directory
+		want    []string
+		wantErr bool
+	}{
+		{"vscode", map[string]string{"storage.json": `{
+			"openedPathsList": {"entries": [
+				{"folderUri": "file:///home/me/api"},
+				{"workspace": {"configPath": "file:///home/me/all.code-workspace"}},
+				{"fileUri": "vscode-remote://ssh/home/me/remote"}
+			]},
+			"backupWorkspaces": {"folders": [{"folderUri": "file:///home/me/my%20blog"}]}
+		}`}, "storage.json", []string{"/home/me/api", "/home/me/my blog"}, false},
+		{"vscode", map[string]string{"storage.json": `{"openedPathsList":`}, "storage.json", nil, true},
+		{"vscode", nil, "missing.json", nil, true},
+		{"projectile", map[string]string{"bookmarks.eld": `("~/src/api/" "/home/me/say \"hi\"/")`},
+			"bookmarks.eld", []string{"/home/me/say \"hi\"/", "~/src/api/"}, false},
+		{"zoxide", map[string]string{"z.txt": "  12.5 /home/me/api\n/plain/path\n\n3 /with space/x\nnotascore /odd\n"},
+			"z.txt", []string{"/home/me/api", "/plain/path", "/with space/x", "notascore /odd"}, false},
+		{"tmuxinator", map[string]string{
+			"tmux/api.yml":    "name: api\nroot: ~/src/api # main\nwindows:\n  - root: /ignored\n",
+			"tmux/blog.yaml":  "root: \"/src/blog\"\n",
+			"tmux/noroot.yml": "name: x\n",
+			"tmux/notes.txt":  "root: /skipped\n",
+		}, "tmux", []string{"api=~/src/api", "blog=/src/blog"}, false},
+		{"tmuxinator", nil, "nowhere", nil, true},
+		{"smug", map[string]string{"work.yml": "session: work\nroot: /src/work\n"},
+			"work.yml", []string{"work=/src/work"}, false},
+		{"paths", map[string]string{"list": "# projects\n/src/api\n\n  /src/blog  \n"},
+			"list", []string{"/src/api", "/src/blog"}, false},
+		{"paths", nil, "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source+"/"+tt.arg, func(t *testing.T) {
+			src, ok := findImportSource(tt.source)
+			if !ok {
+				t.Fatalf("no source %q", tt.source)
+			}
+			dir := t.TempDir()
+			writeTree(t, dir, tt.files)
+			arg := tt.arg
+			if arg != "" {
+				arg = filepath.Join(dir, arg)
+			}
+			entries, err := src.read(arg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("read(%s) error = %v, want error %v", tt.arg, err, tt.wantErr)
+			}
+			var got []string
+			for _, e := range entries {
+				if e.name != "" {
+					got = append(got, e.name+"="+e.path)
+				} else {
+					got = append(got, e.path)
+				}
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("read(%s) = %q, want %q", tt.arg, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportCandidates(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"api/go.mod": "", "blog/README.md": "My blog", "known/go.mod": ""})
+	entries := []importEntry{
+		{path: filepath.Join(dir, "api")},
+		{name: "weblog", path: filepath.Join(dir, "blog") + "/"},
+		{path: filepath.Join(dir, "api", ".")},
+		{path: filepath.Join(dir, "known")},
+		{path: filepath.Join(dir, "gone")},
+	}
+	known := map[string]bool{filepath.Join(dir, "known"): true}
+
+	var got []string
+	for _, c := range importCandidates(entries, known) {
+		rel, _ := filepath.Rel(dir, c.project.Path)
+		entry := strings.Join([]string{rel, c.project.Name, c.project.Description}, "|")
+		if c.selected {
+			entry += " ✓"
+		}
+		got = append(got, entry)
+	}
+	want := []string{"api|api| ✓", "blog|weblog|My blog ✓", "gone|gone|"}
+	if !slices.Equal(got, want) {
+		t.Errorf("importCandidates() = %q, want %q", got, want)
+	}
+}
+
+func TestYAMLTopLevelValue(t *testing.T) {
+	data := []byte("# root: /comment\nname: 'api'\n  root: /nested\nroot: /src/api # trailing\nempty:\n")
+	tests := []struct{ key, want string }{
+		{"name", "api"},
+		{"root", "/src/api"},
+		{"empty", ""},
+		{"missing", ""},
+	}
+	for _, tt := range tests {
+		if got := yamlTopLevelValue(data, tt.key); got != tt.want {
+			t.Errorf("yamlTopLevelValue(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,529 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// keyMap holds the key bindings of the list view, the search box, the
+// add/edit form and the trash, scan and book views. Any of them can be
+// rebound in the [keys] table of the config.
+type keyMap struct {
+	List   listKeys
+	Filter filterKeys
+	Form   formKeys
+	Trash  trashKeys
+	Scan   scanKeys
+	Books  booksKeys
+}
+
+type listKeys struct {
+	Up, Down, Select, Open                      key.Binding
+	Add, Edit, Delete, Undo, Redo               key.Binding
+	Pin, PinUp, PinDown                         key.Binding
+	Search, Scan, Trash, Books                  key.Binding
+	Relocate, Merge, Export, Reload, Help, Quit key.Binding
+}
+
+type filterKeys struct {
+	Up, Down, Open, Clear key.Binding
+}
+
+type formKeys struct {
+	Complete, Next, Prev, Submit, Cancel key.Binding
+}
+
+type trashKeys struct {
+	Up, Down, Restore, Purge, Empty, Close key.Binding
+}
+
+type scanKeys struct {
+	Up, Down, Toggle, ToggleAll, Import, Cancel key.Binding
+}
+
+type booksKeys struct {
+	Up, Down, Switch, Close key.Binding
+}
+
+// keymap is the keymap in effect, set up by applyKeys.
+var keymap = defaultKeyMap()
+
+// quitKey always quits, whatever the config says, so it can't be rebound.
+const quitKey = "ctrl+c"
+
+func defaultKeyMap() keyMap {
+	return keyMap{
+		List: listKeys{
+			Up:       bind("move up", "k", "up"),
+			Down:     bind("move down", "j", "down"),
+			Select:   bind("open, or pick with --print-path", "enter"),
+			Open:     bind("open", "o"),
+			Add:      bind("add a project", "a"),
+			Edit:     bind("edit", "e"),
+			Delete:   bind("move to the trash", "d"),
+			Undo:     bind("undo", "u"),
+			Redo:     bind("redo", "ctrl+r"),
+			Pin:      bind("pin / unpin", "p"),
+			PinUp:    bind("move pin up", "K"),
+			PinDown:  bind("move pin down", "J"),
+			Search:   bind("search", "/"),
+			Scan:     bind("scan for projects", "S"),
+			Trash:    bind("browse the trash", "T"),
+			Books:    bind("switch phonebook", "B"),
+			Relocate: bind("locate a moved project", "L"),
+			Merge:    bind("merge duplicates", "M"),
+			Export:   bind("export the listed projects", "E"),
+			Reload:   bind("reload from disk", "r"),
+			Help:     bind("help", "?"),
+			Quit:     bind("quit", "q"),
+		},
+		Filter: filterKeys{
+			Up:    bind("previous result", "up", "ctrl+p"),
+			Down:  bind("next result", "down", "ctrl+n"),
+			Open:  bind("open", "enter"),
+			Clear: bind("clear search", "esc"),
+		},
+		Form: formKeys{
+			Complete: bind("complete path, or next field", "tab"),
+			Next:     bind("next field", "down"),
+			Prev:     bind("previous field", "shift+tab", "up"),
+			Submit:   bind("submit, or next field", "enter"),
+			Cancel:   bind("cancel", "esc"),
+		},
+		Trash: trashKeys{
+			Up:      bind("move up", "k", "up"),
+			Down:    bind("move down", "j", "down"),
+			Restore: bind("restore", "r", "enter"),
+			Purge:   bind("delete for good", "x", "d"),
+			Empty:   bind("empty the trash", "X"),
+			Close:   bind("back", "esc", "q"),
+		},
+		Scan: scanKeys{
+			Up:        bind("move up", "k", "up"),
+			Down:      bind("move down", "j", "down"),
+			Toggle:    bind("toggle", " ", "x"),
+			ToggleAll: bind("toggle all", "a"),
+			Import:    bind("import the selected", "enter"),
+			Cancel:    bind("cancel", "esc", "q"),
+		},
+		Books: booksKeys{
+			Up:     bind("move up", "k", "up"),
+			Down:   bind("move down", "j", "down"),
+			Switch: bind("switch", "enter"),
+			Close:  bind("back", "esc", "q"),
+		},
+	}
+}
+
+func bind(desc string, keys ...string) key.Binding {
+	return key.NewBinding(key.WithKeys(keys...), key.WithHelp(keyLabel(keys), desc))
+}
+
+// keyNames are shorter names for keys in the help.
+var keyNames = map[string]string{
+	"enter": "↵", "up": "↑", "down": "↓", "left": "←", "right": "→", " ": "space",
+}
+
+// keyLabel describes keys for the help, e.g. "k/↑" or "^r".
+func keyLabel(keys []string) string {
+	labels := make([]string, len(keys))
+	for i, k := range keys {
+		if name, ok := keyNames[k]; ok {
+			k = name
+		}
+		labels[i] = strings.Replace(k, "ctrl+", "^", 1)
+	}
+	return strings.Join(labels, "/")
+}
+
+// keyAction is a binding and its name in the config.
+type keyAction struct {
+	name    string
+	binding *key.Binding
+}
+
+// keyMode is a group of bindings active at the same time, which therefore
+// can't share keys. table is where the config overrides them: list bindings
+// sit in [keys] itself, the others in [keys.<table>].
+type keyMode struct {
+	table, title string
+	actions      []keyAction
+}
+
+// modes lists the bindings of km by mode, in the order the help shows them.
+func (km *keyMap) modes() []keyMode {
+	l, f, a := &km.List, &km.Filter, &km.Form
+	t, s, b := &km.Trash, &km.Scan, &km.Books
+	return []keyMode{
+		{"", "List", []keyAction{
+			{"up", &l.Up}, {"down", &l.Down}, {"select", &l.Select}, {"open", &l.Open},
+			{"add", &l.Add}, {"edit", &l.Edit}, {"delete", &l.Delete},
+			{"undo", &l.Undo}, {"redo", &l.Redo},
+			{"pin", &l.Pin}, {"pin_up", &l.PinUp}, {"pin_down", &l.PinDown},
+			{"search", &l.Search}, {"scan", &l.Scan}, {"trash", &l.Trash}, {"books", &l.Books},
+			{"relocate", &l.Relocate}, {"merge", &l.Merge}, {"export", &l.Export},
+			{"reload", &l.Reload}, {"help", &l.Help}, {"quit", &l.Quit},
+		}},
+		{"filter", "Search", []keyAction{
+			{"up", &f.Up}, {"down", &f.Down}, {"open", &f.Open}, {"clear", &f.Clear},
+		}},
+		{"form", "Add / Edit", []keyAction{
+			{"complete", &a.Complete}, {"next", &a.Next}, {"prev", &a.Prev},
+			{"submit", &a.Submit}, {"cancel", &a.Cancel},
+		}},
+		{"trash_view", "Trash", []keyAction{
+			{"up", &t.Up}, {"down", &t.Down}, {"restore", &t.Restore},
+			{"purge", &t.Purge}, {"empty", &t.Empty}, {"close", &t.Close},
+		}},
+		{"scan_view", "Scan", []keyAction{
+			{"up", &s.Up}, {"down", &s.Down}, {"toggle", &s.Toggle},
+			{"toggle_all", &s.ToggleAll}, {"import", &s.Import}, {"cancel", &s.Cancel},
+		}},
+		{"books_view", "Books", []keyAction{
+			{"up", &b.Up}, {"down", &b.Down}, {"switch", &b.Switch}, {"close", &b.Close},
+		}},
+	}
+}
+
+// find returns the action called name.
+func (mode keyMode) find(name string) (keyAction, bool) {
+	for _, a := range mode.actions {
+		if a.name == name {
+			return a, true
+		}
+	}
+	return keyAction{}, false
+}
+
+// modeTable returns the part of the [keys] table that configures mode, and
+// whether there is one.
+func modeTable(keys map[string]any, mode keyMode) (map[string]any, bool) {
+	if mode.table == "" {
+		return keys, keys != nil
+	}
+	table, ok := keys[mode.table].(map[string]any)
+	return table, ok
+}
+
+// keyList reads a binding from the config: a key or a list of keys. The
+// space bar is written "space".
+func keyList(v any) ([]string, error) {
+	switch v := v.(type) {
+	case string:
+		return spaceKeys([]string{v}), nil
+	case []string:
+		return spaceKeys(v), nil
+	case []any:
+		keys := make([]string, len(v))
+		for i, k := range v {
+			s, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("%v is not a key", k)
+			}
+			keys[i] = s
+		}
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("no keys given")
+		}
+		return spaceKeys(keys), nil
+	}
+	return nil, fmt.Errorf("%v is not a key or a list of keys", v)
+}
+
+// spaceKeys turns "space" into " ", the name Bubble Tea gives the space bar.
+func spaceKeys(keys []string) []string {
+	out := make([]string, len(keys))
+	for i, k := range keys {
+		if k == "space" {
+			k = " "
+		}
+		out[i] = k
+	}
+	return out
+}
+
+// validateKeys checks the [keys] table, dropping bindings that name unknown
+// actions, use the quit key or clash with another binding of the same mode.
+func validateKeys(keys map[string]any) []string {
+	var problems []string
+	report := func(format string, a ...any) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+	}
+	defaults := defaultKeyMap()
+	modes := defaults.modes()
+
+	for _, mode := range modes {
+		prefix := "keys"
+		if mode.table != "" {
+			prefix += "." + mode.table
+			if _, ok := keys[mode.table]; ok {
+				if _, ok := modeTable(keys, mode); !ok {
+					report("%s must be a table of bindings", prefix)
+					delete(keys, mode.table)
+				}
+			}
+		}
+		table, _ := modeTable(keys, mode)
+
+		for _, name := range sortedKeys(table) {
+			if mode.table == "" && isModeTable(modes, name) {
+				continue
+			}
+			if _, ok := mode.find(name); !ok {
+				var known []string
+				for _, a := range mode.actions {
+					known = append(known, a.name)
+				}
+				report("%s: unknown action %q (known: %s)", prefix, name, strings.Join(known, ", "))
+				delete(table, name)
+				continue
+			}
+			list, err := keyList(table[name])
+			if err != nil {
+				report("%s.%s: %v", prefix, name, err)
+				delete(table, name)
+				continue
+			}
+			for _, k := range list {
+				if k == "" || (k != " " && strings.ContainsAny(k, " \t")) {
+					report("%s.%s: %q is not a key", prefix, name, k)
+					delete(table, name)
+					break
+				}
+				if k == quitKey {
+					report("%s.%s: %s always quits and can't be rebound", prefix, name, k)
+					delete(table, name)
+					break
+				}
+			}
+			if _, ok := table[name]; ok {
+				table[name] = list
+			}
+		}
+
+		// A rebound key may land on another action's key. Drop the
+		// rebindings involved until every key has one action.
+		for {
+			byKey := make(map[string][]string)
+			for _, a := range mode.actions {
+				list := a.binding.Keys()
+				if v, ok := table[a.name]; ok {
+					list, _ = keyList(v)
+				}
+				for _, k := range list {
+					byKey[k] = append(byKey[k], a.name)
+				}
+			}
+			clash := false
+			for _, k := range sortedKeys(byKey) {
+				actions := byKey[k]
+				if len(actions) < 2 {
+					continue
+				}
+				clash = true
+				report("%s: %s is bound to both %s; keeping the defaults", prefix, k, strings.Join(actions, " and "))
+				for _, action := range actions {
+					delete(table, action)
+				}
+			}
+			if !clash {
+				break
+			}
+		}
+	}
+	return problems
+}
+
+func isModeTable(modes []keyMode, name string) bool {
+	for _, mode := range modes {
+		if mode.table != "" && mode.table == name {
+			return true
+		}
+	}
+	return false
+}
+
+// applyKeys sets up keymap from the defaults and validated overrides.
+func applyKeys(keys map[string]any) {
+	km := defaultKeyMap()
+	for _, mode := range km.modes() {
+		table, _ := modeTable(keys, mode)
+		for _, a := range mode.actions {
+			v, ok := table[a.name]
+			if !ok {
+				continue
+			}
+			list, err := keyList(v)
+			if err != nil {
+				continue
+			}
+			a.binding.SetKeys(list...)
+			a.binding.SetHelp(keyLabel(list), a.binding.Help().Desc)
+		}
+	}
+	keymap = km
+}
+
+// reopens reports whether msg is open, the key that opened the view whose
+// bindings are in [keys.<table>], and none of the view's own keys, which
+// come first. Pressing it again closes the view.
+func reopens(msg tea.KeyMsg, open key.Binding, table string) bool {
+	for _, mode := range keymap.modes() {
+		if mode.table != table {
+			continue
+		}
+		for _, a := range mode.actions {
+			if key.Matches(msg, *a.binding) {
+				return false
+			}
+		}
+	}
+	return key.Matches(msg, open)
+}
+
+// keyHint returns how to press b, for status messages.
+func keyHint(b key.Binding) string {
+	return b.Help().Key
+}
+
+// joinKeys shows several bindings as one entry of the help bar, by the
+// first key of each.
+func joinKeys(desc string, bs ...key.Binding) key.Binding {
+	var keys, labels []string
+	for _, b := range bs {
+		keys = append(keys, b.Keys()...)
+		labels = append(labels, keyLabel(b.Keys()[:1]))
+	}
+	return key.NewBinding(key.WithKeys(keys...), key.WithHelp(strings.Join(labels, "/"), desc))
+}
+
+// withDesc returns b described as desc.
+func withDesc(b key.Binding, desc string) key.Binding {
+	b.SetHelp(b.Help().Key, desc)
+	return b
+}
+
+// shortHelp is what the help bar of the list view shows.
+func (k listKeys) shortHelp(pickMode bool) []key.Binding {
+	bindings := []key.Binding{joinKeys("move", k.Down, k.Up)}
+	if pickMode {
+		bindings = append(bindings, withDesc(k.Select, "select"), k.Open)
+	} else {
+		bindings = append(bindings, joinKeys("open", k.Open, k.Select))
+	}
+	return append(bindings,
+		withDesc(k.Help, "help"),
+		withDesc(k.Add, "add"),
+		k.Edit,
+		withDesc(k.Scan, "scan"),
+		withDesc(k.Trash, "trash"),
+		withDesc(k.Books, "books"),
+		withDesc(k.Delete, "delete"),
+		joinKeys("undo/redo", k.Undo, k.Redo),
+		withDesc(k.Pin, "pin"),
+		withDesc(k.Relocate, "relocate"),
+		withDesc(k.Merge, "merge"),
+		withDesc(k.Export, "export"),
+		k.Search,
+		withDesc(k.Reload, "reload"),
+		k.Quit,
+	)
+}
+
+// shortHelp is what the help bar shows while searching.
+func (k filterKeys) shortHelp() []key.Binding {
+	return []key.Binding{joinKeys("move", k.Down, k.Up), k.Open, k.Clear}
+}
+
+// shortHelp is what the add and edit forms show below the fields.
+func (k formKeys) shortHelp() []key.Binding {
+	return []key.Binding{withDesc(k.Complete, "autocomplete/next"), withDesc(k.Prev, "previous"), withDesc(k.Submit, "submit"), k.Cancel}
+}
+
+// shortHelp is what the help bar of the trash shows.
+func (k trashKeys) shortHelp() []key.Binding {
+	return []key.Binding{
+		joinKeys("move", k.Down, k.Up),
+		withDesc(k.Restore, "restore"),
+		withDesc(k.Purge, "purge"),
+		withDesc(k.Empty, "empty trash"),
+		k.Close,
+	}
+}
+
+// shortHelp is what the help bar of the scan checklist shows.
+func (k scanKeys) shortHelp() []key.Binding {
+	return []key.Binding{joinKeys("move", k.Down, k.Up), k.Toggle, k.ToggleAll, withDesc(k.Import, "import"), k.Cancel}
+}
+
+// shortHelp is what the help bar of the book picker shows.
+func (k booksKeys) shortHelp() []key.Binding {
+	return []key.Binding{joinKeys("move", k.Down, k.Up), k.Switch, k.Close}
+}
+
+// newHelp returns the help bar, styled like the rest of the UI.
+func newHelp() help.Model {
+	h := help.New()
+	h.ShortSeparator = "  •  "
+	h.Styles.ShortKey = lipgloss.NewStyle().Foreground(primaryColor).Bold(true)
+	h.Styles.ShortDesc = lipgloss.NewStyle().Foreground(mutedColor)
+	h.Styles.ShortSeparator = lipgloss.NewStyle().Foreground(mutedColor)
+	h.Styles.Ellipsis = lipgloss.NewStyle().Foreground(mutedColor)
+	return h
+}
+
+// helpBar renders bindings in the width left over by status, which follows
+// the bar on the same line.
+func (m model) helpBar(bindings []key.Binding, status string) string {
+	h := m.help
+	if h.Width > 0 {
+		// helpStyle pads the bar by a column on each side.
+		h.Width = max(h.Width-2-lipgloss.Width(status), 1)
+	}
+	return helpStyle.Render(h.ShortHelpView(bindings))
+}
+
+// helpView is the full-screen overlay opened with ?, listing every binding
+// in effect by mode.
+func (m model) helpView() string {
+	km := keymap
+	modes := km.modes()
+	section := func(mode keyMode) string {
+		width := 0
+		for _, a := range mode.actions {
+			width = max(width, lipgloss.Width(a.binding.Help().Key))
+		}
+		lines := []string{labelStyle.Render(mode.title)}
+		for _, a := range mode.actions {
+			h := a.binding.Help()
+			lines = append(lines, helpKey(h.Key+strings.Repeat(" ", width-lipgloss.Width(h.Key)), " "+h.Desc))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	// The list gets a column of its own; the other modes share two.
+	column := func(modes []keyMode) string {
+		var sections []string
+		for _, mode := range modes {
+			sections = append(sections, section(mode))
+		}
+		return strings.Join(sections, "\n\n")
+	}
+	body := lipgloss.JoinHorizontal(lipgloss.Top,
+		column(modes[:1]),
+		"    ",
+		column(modes[1:3]),
+		"    ",
+		column(modes[3:]),
+	)
+	footer := helpStyle.Render(fmt.Sprintf("%s quits from anywhere  •  press any key to close", keyLabel([]string{quitKey})))
+
+	return lipgloss.NewStyle().
+		Padding(1, 2).
+		Render(titleStyle.Render(" Keys") + "\n\n" + body + "\n" + footer)
+}
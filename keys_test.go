@@ -0,0 +1,186 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// keysTable decodes the [keys] table of a config the way loadConfig does.
+func keysTable(t *testing.T, config string) map[string]any {
+	t.Helper()
+	var cfg struct {
+		Keys map[string]any `toml:"keys"`
+	}
+	if _, err := toml.Decode(config, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	return cfg.Keys
+}
+
+func TestValidateKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []string // Part of each problem expected, in order
+		kept   []string // Bindings left in [keys], as "table.action" or "action"
+	}{
+		{"empty", "", nil, nil},
+		{"rebinding", "[keys]\ndelete = \"x\"\nopen = [\"o\", \"l\"]\n", nil, []string{"delete", "open"}},
+		{"mode tables", "[keys.filter]\nclear = \"ctrl+g\"\n[keys.form]\nsubmit = \"ctrl+s\"\n", nil, []string{"filter.clear", "form.submit"}},
+		{"unknown action", "[keys]\nexplode = \"x\"\n", []string{`unknown action "explode"`}, nil},
+		{"action of another mode", "[keys.filter]\nadd = \"x\"\n", []string{`keys.filter: unknown action "add"`}, nil},
+		{"not a key", "[keys]\ndelete = \"x y\"\n", []string{"is not a key"}, nil},
+		{"empty key", "[keys]\ndelete = \"\"\n", []string{"is not a key"}, nil},
+		{"not a string", "[keys]\ndelete = 4\n", []string{"is not a key or a list of keys"}, nil},
+		{"empty list", "[keys]\ndelete = []\n", []string{"no keys given"}, nil},
+		{"quit key", "[keys]\nquit = \"ctrl+c\"\n", []string{"ctrl+c always quits"}, nil},
+		{"mode table not a table", "[keys]\nform = \"x\"\n", []string{"keys.form must be a table"}, nil},
+		{"clash with a default", "[keys]\ndelete = \"e\"\nadd = \"n\"\n", []string{"e is bound to both edit and delete"}, []string{"add"}},
+		{"clash between rebindings", "[keys]\ndelete = \"x\"\nedit = \"x\"\n", []string{"x is bound to both edit and delete"}, nil},
+		{"swap", "[keys]\ndelete = \"e\"\nedit = \"d\"\n", nil, []string{"delete", "edit"}},
+		{
+			// Dropping delete = "e" brings back d, which pin now wants.
+			"clash uncovered by dropping another",
+			"[keys]\ndelete = \"e\"\npin = \"d\"\n",
+			[]string{"e is bound to both edit and delete", "d is bound to both delete and pin"},
+			nil,
+		},
+		{"same key in another mode", "[keys]\nsearch = \"ctrl+n\"\n", nil, []string{"search"}},
+		{
+			"view tables",
+			"[keys.trash_view]\npurge = \"D\"\n[keys.scan_view]\ntoggle = \"space\"\n[keys.books_view]\nswitch = [\"l\", \"enter\"]\n",
+			nil,
+			[]string{"trash_view.purge", "scan_view.toggle", "books_view.switch"},
+		},
+		{"clash in a view", "[keys.trash_view]\nempty = \"r\"\n", []string{"keys.trash_view: r is bound to both restore and empty"}, nil},
+		{"action of the list in a view", "[keys.books_view]\nadd = \"a\"\n", []string{`keys.books_view: unknown action "add"`}, nil},
+		{"space among other keys", "[keys.scan_view]\ntoggle = [\"x\", \"space bar\"]\n", []string{"is not a key"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := keysTable(t, tt.config)
+			problems := validateKeys(keys)
+			if len(problems) != len(tt.want) {
+				t.Fatalf("problems %q, want %d", problems, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(problems[i], want) {
+					t.Errorf("problem %q, want one about %s", problems[i], want)
+				}
+			}
+
+			var kept []string
+			defaults := defaultKeyMap()
+			for _, mode := range defaults.modes() {
+				table, _ := modeTable(keys, mode)
+				for _, name := range sortedKeys(table) {
+					if _, ok := mode.find(name); ok {
+						kept = append(kept, strings.TrimPrefix(mode.table+"."+name, "."))
+					}
+				}
+			}
+			if !slices.Equal(kept, tt.kept) {
+				t.Errorf("kept %v, want %v", kept, tt.kept)
+			}
+		})
+	}
+}
+
+func TestApplyKeys(t *testing.T) {
+	t.Cleanup(func() { applyKeys(nil) })
+	keys := keysTable(t, "[keys]\ndelete = \"x\"\nredo = [\"ctrl+y\", \"U\"]\n[keys.filter]\nclear = \"ctrl+g\"\n[keys.scan_view]\ntoggle = \"space\"\n")
+	if problems := validateKeys(keys); len(problems) > 0 {
+		t.Fatal(problems)
+	}
+	applyKeys(keys)
+
+	tests := []struct {
+		name      string
+		binding   []string
+		want      []string
+		wantLabel string
+	}{
+		{"rebound", keymap.List.Delete.Keys(), []string{"x"}, "x"},
+		{"several keys", keymap.List.Redo.Keys(), []string{"ctrl+y", "U"}, "^y/U"},
+		{"untouched", keymap.List.Edit.Keys(), []string{"e"}, "e"},
+		{"mode table", keymap.Filter.Clear.Keys(), []string{"ctrl+g"}, "^g"},
+		{"space", keymap.Scan.Toggle.Keys(), []string{" "}, "space"},
+	}
+	for _, tt := range tests {
+		if !slices.Equal(tt.binding, tt.want) {
+			t.Errorf("%s: keys %v, want %v", tt.name, tt.binding, tt.want)
+		}
+		if got := keyLabel(tt.binding); got != tt.wantLabel {
+			t.Errorf("%s: shown as %q, want %q", tt.name, got, tt.wantLabel)
+		}
+	}
+	if got := keyHint(keymap.List.Redo); got != "^y/U" {
+		t.Errorf("redo is shown as %q, want ^y/U", got)
+	}
+
+	applyKeys(nil)
+	if got := keymap.List.Delete.Keys(); !slices.Equal(got, []string{"d"}) {
+		t.Errorf("delete after resetting = %v, want the default", got)
+	}
+}
+
+func TestKeyLabel(t *testing.T) {
+	tests := []struct {
+		keys []string
+		want string
+	}{
+		{[]string{"d"}, "d"},
+		{[]string{"k", "up"}, "k/↑"},
+		{[]string{"ctrl+r"}, "^r"},
+		{[]string{"enter"}, "↵"},
+		{[]string{"shift+tab", "up"}, "shift+tab/↑"},
+		{[]string{" ", "x"}, "space/x"},
+	}
+	for _, tt := range tests {
+		if got := keyLabel(tt.keys); got != tt.want {
+			t.Errorf("keyLabel(%q) = %q, want %q", tt.keys, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultKeysDontClash(t *testing.T) {
+	km := defaultKeyMap()
+	for _, mode := range km.modes() {
+		seen := make(map[string]string)
+		for _, a := range mode.actions {
+			for _, k := range a.binding.Keys() {
+				if other, ok := seen[k]; ok {
+					t.Errorf("%s: %s is bound to both %s and %s", mode.title, k, other, a.name)
+				}
+				seen[k] = a.name
+			}
+		}
+	}
+}
+
+func TestReopens(t *testing.T) {
+	t.Cleanup(func() { applyKeys(nil) })
+	press := func(k string) tea.KeyMsg {
+		return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+	}
+	if !reopens(press("T"), keymap.List.Trash, "trash_view") {
+		t.Error("T doesn't close the trash")
+	}
+	if reopens(press("x"), keymap.List.Trash, "trash_view") {
+		t.Error("x closes the trash")
+	}
+
+	// Once the trash uses T itself, T no longer closes it.
+	keys := keysTable(t, "[keys.trash_view]\nempty = \"T\"\n")
+	if problems := validateKeys(keys); len(problems) > 0 {
+		t.Fatal(problems)
+	}
+	applyKeys(keys)
+	if reopens(press("T"), keymap.List.Trash, "trash_view") {
+		t.Error("T closes the trash although it empties it")
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// layerRoot is the directory relative paths in layer are resolved against.
+func (c Config) layerRoot(layer LayerConfig) string {
+	switch {
+	case layer.Root != "":
+		return expandPath(layer.Root)
+	case c.SharedRoot != "":
+		return expandPath(c.SharedRoot)
+	}
+	home, _ := os.UserHomeDir()
+	return home
+}
+
+// resolveSharedPath turns a path from a layer, or from PathOverrides, into an
+// absolute path on this machine.
+func resolveSharedPath(path, root string) string {
+	if strings.HasPrefix(path, "~") || filepath.IsAbs(path) {
+		return normalizePath(path)
+	}
+	return normalizePath(filepath.Join(root, path))
+}
+
+// loadLayers reads every configured layer and returns its projects marked as
+// shared. Projects whose directory the user already has in their own file
+// are left out, as the personal entry takes precedence.
+func (m *model) loadLayers(local []Project) ([]Project, error) {
+	own := make(map[string]bool, len(local))
+	for _, p := range local {
+		own[normalizePath(p.Path)] = true
+	}
+
+	var shared []Project
+	var errs []string
+	for _, layer := range m.config.Layers {
+		file := expandPath(layer.File)
+		data, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		format := formatFromExt(file)
+		if format == "" || format == "markdown" {
+			format = "json"
+		}
+		ps, err := parseExport(data, format)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", file, err))
+			continue
+		}
+
+		root := m.config.layerRoot(layer)
+		for _, p := range ps {
+			if override, ok := m.config.PathOverrides[p.Name]; ok {
+				p.Path = override
+			}
+			p.Path = resolveSharedPath(p.Path, root)
+			if own[p.Path] || p.archived() {
+				continue
+			}
+			own[p.Path] = true
+			p.Shared = file
+			p.Pinned = 0
+			shared = append(shared, p)
+		}
+	}
+	if len(errs) > 0 {
+		return shared, fmt.Errorf("reading layers: %s", strings.Join(errs, "; "))
+	}
+	return shared, nil
+}
+
+// splitShared separates the user's own projects from those read from layers.
+func splitShared(ps []Project) (local, shared []Project) {
+	local = []Project{}
+	for _, p := range ps {
+		if p.Shared != "" {
+			shared = append(shared, p)
+		} else {
+			local = append(local, p)
+		}
+	}
+	return local, shared
+}
+
+// withLayers appends the projects of all layers to local, keeping any error
+// to show once loading is done.
+func (m *model) withLayers(local []Project) []Project {
+	shared, err := m.loadLayers(local)
+	m.layerErr = err
+	// Open history only ranks projects; a file we can't read just leaves
+	// shared projects unranked.
+	opens, _ := readSharedOpens(sharedOpensFile(m.projectsFile))
+	for i, p := range shared {
+		if h, ok := opens[projectKey(p)]; ok {
+			shared[i].OpenCount, shared[i].Opens = h.OpenCount, h.Opens
+		}
+	}
+	return append(local, shared...)
+}
+
+// openHistory is the open history of a shared project. Layers are read-only
+// and common to everyone, so each user keeps their own next to their store.
+type openHistory struct {
+	OpenCount int         `json:"open_count"`
+	Opens     []time.Time `json:"opens"`
+}
+
+// sharedOpensFile is where opens of shared projects are kept for the
+// phonebook stored in file. It stays out of sync, like the layer paths the
+// keys are made of.
+func sharedOpensFile(file string) string {
+	return filepath.Join(filepath.Dir(file), "shared-opens", filepath.Base(file))
+}
+
+// readSharedOpens reads the open history of shared projects, by projectKey.
+func readSharedOpens(file string) (map[string]openHistory, error) {
+	opens := make(map[string]openHistory)
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return opens, nil
+	}
+	if err != nil {
+		return opens, err
+	}
+	if err := json.Unmarshal(data, &opens); err != nil {
+		return opens, fmt.Errorf("%s: %w", file, err)
+	}
+	return opens, nil
+}
+
+// recordSharedOpen notes that the shared project with key was opened at t,
+// keeping opens other processes recorded meanwhile.
+func recordSharedOpen(projectsFile, key string, t time.Time) error {
+	file := sharedOpensFile(projectsFile)
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	unlock, err := lockFile(file)
+	if err != nil {
+		return fmt.Errorf("locking shared opens: %w", err)
+	}
+	defer unlock()
+
+	opens, err := readSharedOpens(file)
+	if err != nil {
+		return err
+	}
+	h := opens[key]
+	p := Project{OpenCount: h.OpenCount, Opens: h.Opens}
+	p.recordOpen(t)
+	opens[key] = openHistory{OpenCount: p.OpenCount, Opens: p.Opens}
+	data, err := json.MarshalIndent(opens, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(file, data, 0o644)
+}
+
+// unshadowed drops shared projects whose directory is now in local too.
+func unshadowed(local, shared []Project) []Project {
+	own := make(map[string]bool, len(local))
+	for _, p := range local {
+		own[normalizePath(p.Path)] = true
+	}
+	var out []Project
+	for _, p := range shared {
+		if !own[p.Path] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// guardShared refuses changes to a project that comes from a layer.
+func (m *model) guardShared(idx int) error {
+	if idx >= 0 && idx < len(m.projects) && m.projects[idx].Shared != "" {
+		return fmt.Errorf("'%s' is shared from %s and can't be changed here", m.projects[idx].Name, filepath.Base(m.projects[idx].Shared))
+	}
+	return nil
+}
+
+// sharedKeys are the list-view bindings that would change the selected
+// project, which shared projects don't allow.
+func sharedKeys() []key.Binding {
+	l := keymap.List
+	return []key.Binding{l.Edit, l.Delete, l.Pin, l.PinUp, l.PinDown, l.Relocate, l.Merge}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeLayer writes projects to a JSON layer file in dir.
+func writeLayer(t *testing.T, dir string, projects ...Project) string {
+	t.Helper()
+	data, err := json.Marshal(projects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "team.json")
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// layeredStore returns a model holding local projects with cfg's layers on
+// top.
+func layeredStore(t *testing.T, cfg Config, local ...Project) model {
+	t.Helper()
+	m := storeWith(t, local...)
+	m.config = cfg
+	if err := m.loadProjects(); err != nil {
+		t.Fatal(err)
+	}
+	if m.layerErr != nil {
+		t.Fatal(m.layerErr)
+	}
+	return m
+}
+
+func TestLoadLayers(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "src")
+	trashed := Project{Name: "old", Path: "old", DeletedAt: &t0}
+	file := writeLayer(t, dir,
+		Project{Name: "api", Path: "api", Pinned: 1},
+		Project{Name: "web", Path: "web"},
+		Project{Name: "tools", Path: "/opt/tools"},
+		Project{Name: "mine", Path: "mine"},
+		trashed,
+	)
+	mine := Project{Name: "my-fork", Path: filepath.Join(root, "mine"), CreatedAt: t0}
+
+	tests := []struct {
+		name string
+		cfg  Config
+		want map[string]string
+	}{
+		{
+			"relative to shared_root",
+			Config{Layers: []LayerConfig{{File: file}}, SharedRoot: root},
+			map[string]string{"api": root + "/api", "web": root + "/web", "tools": "/opt/tools"},
+		},
+		{
+			"layer root wins over shared_root",
+			Config{Layers: []LayerConfig{{File: file, Root: filepath.Join(dir, "team")}}, SharedRoot: root},
+			map[string]string{"api": dir + "/team/api", "web": dir + "/team/web", "tools": "/opt/tools", "mine": dir + "/team/mine"},
+		},
+		{
+			"path_overrides",
+			Config{
+				Layers:        []LayerConfig{{File: file}},
+				SharedRoot:    root,
+				PathOverrides: map[string]string{"web": "/work/web", "tools": "bin/tools"},
+			},
+			map[string]string{"api": root + "/api", "web": "/work/web", "tools": root + "/bin/tools"},
+		},
+		{
+			"override onto a personal project is shadowed",
+			Config{
+				Layers:        []LayerConfig{{File: file}},
+				SharedRoot:    root,
+				PathOverrides: map[string]string{"api": root + "/mine/"},
+			},
+			map[string]string{"web": root + "/web", "tools": "/opt/tools"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := layeredStore(t, tt.cfg, mine)
+			_, shared := splitShared(m.projects)
+			got := make(map[string]string)
+			for _, p := range shared {
+				got[p.Name] = p.Path
+				if p.Shared != file || p.Pinned != 0 {
+					t.Errorf("%s: Shared = %q, Pinned = %d; want %q and unpinned", p.Name, p.Shared, p.Pinned, file)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("shared projects %v, want %v", got, tt.want)
+			}
+			if m.projects[0].Name != "my-fork" {
+				t.Errorf("first project %q, want the personal one", m.projects[0].Name)
+			}
+		})
+	}
+}
+
+func TestLoadLayersErrors(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.json")
+	os.WriteFile(bad, []byte("{"), 0o644)
+	good := writeLayer(t, dir, Project{Name: "api", Path: "/src/api"})
+
+	m := storeWith(t)
+	m.config = Config{Layers: []LayerConfig{
+		{File: filepath.Join(dir, "missing.json")},
+		{File: bad},
+		{File: good},
+	}}
+	if err := m.loadProjects(); err != nil {
+		t.Fatal(err)
+	}
+	if m.layerErr == nil || !strings.Contains(m.layerErr.Error(), "bad.json") {
+		t.Errorf("layerErr = %v, want one naming bad.json", m.layerErr)
+	}
+	if len(m.projects) != 1 || m.projects[0].Name != "api" {
+		t.Errorf("projects %v, want the readable layer's", m.projects)
+	}
+}
+
+func TestSharedProjectsAreReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	file := writeLayer(t, dir, Project{Name: "api", Path: "/src/api"})
+	m := layeredStore(t, Config{Layers: []LayerConfig{{File: file}}}, testProject("blog", t0))
+	api := indexOfName(t, &m, "api")
+
+	if err := m.updateProject(api, Project{Name: "renamed", Path: "/src/api"}); err == nil {
+		t.Error("updateProject changed a shared project")
+	}
+	if err := m.deleteProject(api); err == nil {
+		t.Error("deleteProject deleted a shared project")
+	}
+	if err := m.guardShared(indexOfName(t, &m, "blog")); err != nil {
+		t.Errorf("guardShared refused a personal project: %v", err)
+	}
+
+	// Saving keeps shared projects out of the user's file and in the list.
+	if err := m.saveProjects(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(m.projectsFile)
+	if strings.Contains(string(data), "/src/api") {
+		t.Errorf("shared project written to the store:\n%s", data)
+	}
+	if len(m.projects) != 2 {
+		t.Errorf("%d projects after saving, want 2", len(m.projects))
+	}
+
+	// Adding the same directory as a personal project shadows the shared one.
+	m.projects = append(m.projects, Project{Name: "my-api", Path: "/src/api", CreatedAt: t1})
+	if err := m.saveProjects(); err != nil {
+		t.Fatal(err)
+	}
+	local, shared := splitShared(m.projects)
+	if len(local) != 2 || len(shared) != 0 {
+		t.Errorf("after adding it: %d personal and %d shared projects, want 2 and 0", len(local), len(shared))
+	}
+}
+
+func TestSharedOpensAreRemembered(t *testing.T) {
+	file := writeLayer(t, t.TempDir(), Project{Name: "api", Path: "/src/api"})
+	m := layeredStore(t, Config{Layers: []LayerConfig{{File: file}}}, testProject("blog", t0))
+	before, _ := os.ReadFile(m.projectsFile)
+
+	for i := 0; i < 2; i++ {
+		if err := m.markOpened(indexOfName(t, &m, "api")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if data, _ := os.ReadFile(m.projectsFile); string(data) != string(before) {
+		t.Errorf("opening a shared project changed the store:\n%s", data)
+	}
+
+	// Another session sees the opens.
+	if err := m.loadProjects(); err != nil {
+		t.Fatal(err)
+	}
+	if p := m.projects[indexOfName(t, &m, "api")]; p.OpenCount != 2 || len(p.Opens) != 2 {
+		t.Errorf("after reloading: %d opens (%d times kept), want 2", p.OpenCount, len(p.Opens))
+	}
+}
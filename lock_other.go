@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+// lockFile is a no-op on platforms without flock; change detection in
+// saveProjects still catches most concurrent edits.
+func lockFile(path string) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,40 @@
+//go:build unix
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockFileExcludes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "projects.json")
+	unlock, err := lockFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan func())
+	go func() {
+		second, err := lockFile(path)
+		if err != nil {
+			t.Error(err)
+			second = func() {}
+		}
+		acquired <- second
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("a second lock was taken while the first was held")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case second := <-acquired:
+		second()
+	case <-time.After(time.Second):
+		t.Fatal("the lock wasn't handed over after release")
+	}
+}
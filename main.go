@@ -1,16 +1,20 @@
 package main
 
 import (
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
 	"time"
 	"unicode"
 
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
@@ -18,12 +22,63 @@ import (
 )
 
 type Project struct {
-	Name        string    `json:"name"`
-	Path        string    `json:"path"`
-	Tag         string    `json:"tag"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          string      `json:"id"` // ULID, assigned when the project is added
+	Name        string      `json:"name"`
+	Path        string      `json:"path"`
+	Tags        []string    `json:"tags,omitempty"`
+	Description string      `json:"description"`
+	Opener      string      `json:"opener,omitempty"`
+	Remote      string      `json:"remote,omitempty"` // Git remote URL, used to find the project if it moves
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"` // Last metadata edit
+	OpenCount   int         `json:"open_count,omitempty"`
+	Opens       []time.Time `json:"opens,omitempty"`      // Most recent opens, oldest first
+	Pinned      int         `json:"pinned,omitempty"`     // Position among pinned projects, 0 if not pinned
+	DeletedAt   *time.Time  `json:"deleted_at,omitempty"` // Set while the project is in the trash
+	Book        string      `json:"-"`                    // Book the project came from, in the all-books view
+	Shared      string      `json:"-"`                    // Layer file a read-only shared project came from
+}
+
+// UnmarshalJSON also accepts the single comma-separated "tag" field written
+// by older versions and splits it into Tags.
+func (p *Project) UnmarshalJSON(data []byte) error {
+	type plainProject Project
+	var raw struct {
+		plainProject
+		Tag string `json:"tag"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*p = Project(raw.plainProject)
+	if len(p.Tags) == 0 && raw.Tag != "" {
+		p.Tags = splitTags(raw.Tag)
+	}
+	return nil
+}
+
+// splitTags parses a comma-separated tag list, dropping blanks and duplicates.
+func splitTags(s string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, t := range strings.Split(s, ",") {
+		t = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(t), "#"))
+		if t == "" || seen[strings.ToLower(t)] {
+			continue
+		}
+		seen[strings.ToLower(t)] = true
+		tags = append(tags, t)
+	}
+	return tags
+}
+
+// renderTags renders each tag as its own chip, highlighting query matches.
+func renderTags(tags []string, query string) string {
+	chips := make([]string, len(tags))
+	for i, t := range tags {
+		chips[i] = tagStyle.Render("#" + highlightMatches(query, t))
+	}
+	return strings.Join(chips, " ")
 }
 
 type fuzzyMatch struct {
@@ -31,155 +86,198 @@ type fuzzyMatch struct {
 	score int
 }
 
+// Color palette, set from the current theme by applyTheme.
+var (
+	primaryColor   lipgloss.TerminalColor
+	accentColor    lipgloss.TerminalColor
+	successColor   lipgloss.TerminalColor
+	mutedColor     lipgloss.TerminalColor
+	brightColor    lipgloss.TerminalColor
+	bgColor        lipgloss.TerminalColor
+	highlightColor lipgloss.TerminalColor
+	warningColor   lipgloss.TerminalColor
+	textColor      lipgloss.TerminalColor
+	surfaceColor   lipgloss.TerminalColor
+	inverseColor   lipgloss.TerminalColor
+)
+
+// Styles built from the palette by buildStyles.
 var (
-	// Color palette - Enhanced
-	primaryColor   = lipgloss.Color("#A78BFA") // Lighter purple
-	accentColor    = lipgloss.Color("#F472B6") // Pink
-	successColor   = lipgloss.Color("#34D399") // Green
-	mutedColor     = lipgloss.Color("#9CA3AF") // Gray
-	brightColor    = lipgloss.Color("#FBBF24") // Amber
-	bgColor        = lipgloss.Color("#111827") // Darker bg
-	highlightColor = lipgloss.Color("#60A5FA") // Blue
-	warningColor   = lipgloss.Color("#FB923C") // Orange
-	textColor      = lipgloss.Color("#F3F4F6") // Light text
+	titleStyle          lipgloss.Style
+	subtitleStyle       lipgloss.Style
+	leftPanelStyle      lipgloss.Style
+	rightPanelStyle     lipgloss.Style
+	selectedItemStyle   lipgloss.Style
+	normalItemStyle     lipgloss.Style
+	tagStyle            lipgloss.Style
+	pathStyle           lipgloss.Style
+	matchHighlightStyle lipgloss.Style
+	helpStyle           lipgloss.Style
+	statusStyle         lipgloss.Style
+	errorStyle          lipgloss.Style
+	warningStyle        lipgloss.Style
+	formTitleStyle      lipgloss.Style
+	labelStyle          lipgloss.Style
+	focusedButtonStyle  lipgloss.Style
+	blurredButtonStyle  lipgloss.Style
+	detailLabelStyle    lipgloss.Style
+	detailValueStyle    lipgloss.Style
+	dividerStyle        lipgloss.Style
+	filterBoxStyle      lipgloss.Style
+	counterStyle        lipgloss.Style
+)
 
+func init() {
+	applyTheme(darkTheme)
+}
+
+// buildStyles creates the styles from the color palette. applyTheme runs it
+// whenever the palette changes.
+func buildStyles() {
 	// Title styles
 	titleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(primaryColor).
-			MarginBottom(1).
-			MarginTop(1).
-			Padding(0, 1)
+		Bold(true).
+		Foreground(primaryColor).
+		MarginBottom(1).
+		MarginTop(1).
+		Padding(0, 1)
 
 	subtitleStyle = lipgloss.NewStyle().
-			Foreground(mutedColor).
-			Italic(true)
+		Foreground(mutedColor).
+		Italic(true)
 
 	// Container styles
 	leftPanelStyle = lipgloss.NewStyle().
-			Width(45).
-			Padding(1, 2).
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(primaryColor)
+		Width(45).
+		Padding(1, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(primaryColor)
 
 	rightPanelStyle = lipgloss.NewStyle().
-			Padding(1, 2).
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(accentColor)
+		Padding(1, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(accentColor)
 
 	// List item styles
 	selectedItemStyle = lipgloss.NewStyle().
-				Foreground(brightColor).
-				Bold(true).
-				PaddingLeft(1).
-				Background(lipgloss.Color("#1F2937"))
+		Foreground(brightColor).
+		Bold(true).
+		PaddingLeft(1).
+		Background(surfaceColor)
 
 	normalItemStyle = lipgloss.NewStyle().
-			Foreground(textColor).
-			PaddingLeft(3)
+		Foreground(textColor).
+		PaddingLeft(3)
 
 	tagStyle = lipgloss.NewStyle().
-			Foreground(highlightColor).
-			Bold(true)
+		Foreground(highlightColor).
+		Bold(true)
 
 	pathStyle = lipgloss.NewStyle().
-			Foreground(mutedColor).
-			Italic(true)
+		Foreground(mutedColor).
+		Italic(true)
 
 	matchHighlightStyle = lipgloss.NewStyle().
-				Foreground(accentColor).
-				Bold(true).
-				Underline(true)
+		Foreground(accentColor).
+		Bold(true).
+		Underline(true)
 
 	// Help and status styles
 	helpStyle = lipgloss.NewStyle().
-			Foreground(mutedColor).
-			MarginTop(1).
-			Padding(0, 1)
+		Foreground(mutedColor).
+		MarginTop(1).
+		Padding(0, 1)
 
 	statusStyle = lipgloss.NewStyle().
-			Foreground(successColor).
-			Bold(true).
-			MarginLeft(2)
+		Foreground(successColor).
+		Bold(true).
+		MarginLeft(2)
 
 	errorStyle = lipgloss.NewStyle().
-			Foreground(accentColor).
-			Bold(true).
-			MarginLeft(2)
+		Foreground(accentColor).
+		Bold(true).
+		MarginLeft(2)
 
 	warningStyle = lipgloss.NewStyle().
-			Foreground(warningColor).
-			Italic(true).
-			MarginTop(0)
+		Foreground(warningColor).
+		Italic(true).
+		MarginTop(0)
 
 	// Form styles
 	formTitleStyle = lipgloss.NewStyle().
-			Foreground(primaryColor).
-			Bold(true).
-			MarginBottom(1)
+		Foreground(primaryColor).
+		Bold(true).
+		MarginBottom(1)
 
 	labelStyle = lipgloss.NewStyle().
-			Foreground(highlightColor).
-			Bold(true).
-			MarginBottom(0).
-			MarginTop(1)
+		Foreground(highlightColor).
+		Bold(true).
+		MarginBottom(0).
+		MarginTop(1)
 
 	focusedButtonStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("#FFFFFF")).
-				Background(primaryColor).
-				Padding(0, 3).
-				Bold(true).
-				MarginTop(1).
-				BorderStyle(lipgloss.RoundedBorder()).
-				BorderForeground(primaryColor)
+		Foreground(inverseColor).
+		Background(primaryColor).
+		Padding(0, 3).
+		Bold(true).
+		MarginTop(1).
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(primaryColor)
 
 	blurredButtonStyle = lipgloss.NewStyle().
-				Foreground(mutedColor).
-				Padding(0, 3).
-				MarginTop(1).
-				BorderStyle(lipgloss.RoundedBorder()).
-				BorderForeground(mutedColor)
+		Foreground(mutedColor).
+		Padding(0, 3).
+		MarginTop(1).
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(mutedColor)
 
 	// Detail view styles
 	detailLabelStyle = lipgloss.NewStyle().
-				Foreground(primaryColor).
-				Bold(true).
-				Underline(true)
+		Foreground(primaryColor).
+		Bold(true).
+		Underline(true)
 
 	detailValueStyle = lipgloss.NewStyle().
-				Foreground(textColor).
-				MarginLeft(1)
+		Foreground(textColor).
+		MarginLeft(1)
 
 	// Divider
 	dividerStyle = lipgloss.NewStyle().
-			Foreground(mutedColor).
-			MarginTop(1).
-			MarginBottom(1)
+		Foreground(mutedColor).
+		MarginTop(1).
+		MarginBottom(1)
 
 	// Filter box style
 	filterBoxStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(primaryColor).
-			Padding(0, 1).
-			Width(40).
-			Background(lipgloss.Color("#1F2937"))
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(primaryColor).
+		Padding(0, 1).
+		Width(40).
+		Background(surfaceColor)
 
 	// Counter badge style
 	counterStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FFFFFF")).
-			Background(highlightColor).
-			Padding(0, 1).
-			Bold(true).
-			MarginLeft(1)
-)
+		Foreground(inverseColor).
+		Background(highlightColor).
+		Padding(0, 1).
+		Bold(true).
+		MarginLeft(1)
+}
 
-type editorFinishedMsg struct{ err error }
+type editorFinishedMsg struct {
+	err      error
+	detached bool
+}
 
 type viewMode int
 
 const (
 	viewList viewMode = iota
 	viewAdd
+	viewEdit
+	viewScan
+	viewTrash
+	viewBooks
 )
 
 type model struct {
@@ -192,6 +290,7 @@ type model struct {
 	leftWidth        int
 	ready            bool
 	projectsFile     string
+	config           Config
 	statusMessage    string
 	isError          bool
 	mode             viewMode
@@ -199,26 +298,98 @@ type model struct {
 	addFocusIndex    int
 	pathValidation   string
 	autocompleteOpts []string
-	filterQuery      string // Store current filter query
+	filterQuery      string    // Store current filter query
+	highlight        string    // Part of filterQuery to highlight in the list
+	editKey          string    // Key of the project being edited in viewEdit
+	pickMode         bool      // Enter selects a project and quits instead of opening it
+	picked           string    // Path chosen in pick mode
+	openErr          error     // Failure to record the last open, shown once it has happened
+	storeErr         error     // Set when projects.json couldn't be parsed; blocks saving
+	base             []Project // Projects as last loaded or saved, for merging
+	disk             fileState // Version of projects.json that base came from
+	confirm          *confirmPrompt
+	gitCache         map[string]gitInfo // Git status by project path
+	gitPending       map[string]bool    // Paths with a git status fetch in flight
+	scanning         bool               // A directory scan is running
+	scanResults      []scanCandidate    // Checklist shown in viewScan
+	scanCursor       int
+	scanTitle        string // Header of viewScan
+	scanQuit         bool   // Quit after the scan checklist is done (import --select)
+	autoTags         string // Tags input value last filled in by detection
+	undoStack        []undoEntry
+	redoStack        []undoEntry
+	pendingUndo      *pendingUndo // Checkpoint awaiting the save of its change
+	trashIdxs        []int        // Archived projects shown in viewTrash
+	trashCursor      int
+	exporting        bool     // Waiting for the export format key
+	book             string   // Name of the book in projectsFile
+	allBooks         bool     // Showing every book, read-only
+	bookNames        []string // Books listed in viewBooks
+	bookCursor       int
+	missing          map[string]bool   // Project paths that no longer exist
+	duplicate        map[string]bool   // Project paths registered more than once
+	nested           map[string]string // Project path -> name of the project containing it
+	layerErr         error             // Problem reading a shared layer, shown at startup
+	help             help.Model        // Help bar, as wide as the window
+	showHelp         bool              // Showing the full-screen key overlay
+}
+
+// confirmPrompt is a yes/no question shown in the status bar. onYes runs when
+// the user answers y and returns the status message to show.
+type confirmPrompt struct {
+	question string
+	onYes    func(m *model) (string, error)
 }
 
 func initialModel() model {
-	home, _ := os.UserHomeDir()
-	projectsFile := filepath.Join(home, ".config", "projects", "projects.json")
+	m := newModel()
+	if err := m.load(); err != nil {
+		m.statusMessage = fmt.Sprintf("Error loading projects: %v", err)
+		m.isError = true
+
+		var corrupt *corruptStoreError
+		if errors.As(err, &corrupt) && corrupt.backup != "" {
+			backup := corrupt.backup
+			m.confirm = &confirmPrompt{
+				question: fmt.Sprintf("projects.json is corrupt. Restore from %s?", filepath.Base(backup)),
+				onYes: func(m *model) (string, error) {
+					if err := m.recoverFromBackup(backup); err != nil {
+						return "", err
+					}
+					return fmt.Sprintf("✓ Restored %d projects from %s", len(m.projects), filepath.Base(backup)), nil
+				},
+			}
+		}
+	} else if m.layerErr != nil {
+		m.statusMessage = fmt.Sprintf("Error: %v", m.layerErr)
+		m.isError = true
+	} else if len(configProblems) > 0 {
+		m.statusMessage = configSummary()
+		m.isError = true
+	}
+	m.applyFilter("")
+
+	return m
+}
+
+// newModel builds a model with its widgets set up but nothing loaded from disk.
+func newModel() model {
+	book := activeBook()
+	projectsFile := bookFile(book)
 	os.MkdirAll(filepath.Dir(projectsFile), 0o755)
 
 	ti := textinput.New()
-	ti.Placeholder = "Press / to search projects..."
+	ti.Placeholder = fmt.Sprintf("Press %s to search (tag:go -path:tmp)...", keyHint(keymap.List.Search))
 	ti.PlaceholderStyle = lipgloss.NewStyle().Foreground(mutedColor).Italic(true)
 	ti.PromptStyle = lipgloss.NewStyle().Foreground(primaryColor).Bold(true)
 	ti.TextStyle = lipgloss.NewStyle().Foreground(textColor)
 	ti.CharLimit = 156
-	ti.Width = 38
+	ti.Width = userConfig.listWidth() - 7
 
 	vp := viewport.New(20, 10)
 	vp.SetContent("")
 
-	inputs := make([]textinput.Model, 4)
+	inputs := make([]textinput.Model, 5)
 	inputStyles := lipgloss.NewStyle().Foreground(textColor)
 	promptStyle := lipgloss.NewStyle().Foreground(primaryColor).Bold(true)
 
@@ -243,7 +414,7 @@ func initialModel() model {
 	inputs[2].PlaceholderStyle = lipgloss.NewStyle().Foreground(mutedColor)
 	inputs[2].PromptStyle = promptStyle
 	inputs[2].TextStyle = inputStyles
-	inputs[2].CharLimit = 50
+	inputs[2].CharLimit = 200
 	inputs[2].Width = 60
 
 	inputs[3] = textinput.New()
@@ -254,65 +425,307 @@ func initialModel() model {
 	inputs[3].CharLimit = 500
 	inputs[3].Width = 60
 
+	inputs[4] = textinput.New()
+	inputs[4].Placeholder = "hx {path}, code {path} & (blank = default editor)"
+	inputs[4].PlaceholderStyle = lipgloss.NewStyle().Foreground(mutedColor)
+	inputs[4].PromptStyle = promptStyle
+	inputs[4].TextStyle = inputStyles
+	inputs[4].CharLimit = 200
+	inputs[4].Width = 60
+
 	m := model{
 		projectsFile: projectsFile,
-		leftWidth:    45,
+		book:         book,
+		leftWidth:    userConfig.listWidth(),
 		viewport:     vp,
 		textInput:    ti,
 		mode:         viewList,
 		addInputs:    inputs,
+		gitCache:     make(map[string]gitInfo),
+		gitPending:   make(map[string]bool),
+		missing:      make(map[string]bool),
+		duplicate:    make(map[string]bool),
+		nested:       make(map[string]string),
+		help:         newHelp(),
 	}
 
-	if err := m.loadProjects(); err != nil {
-		m.statusMessage = fmt.Sprintf("Error loading projects: %v", err)
-		m.isError = true
-	}
-	m.applyFilter("")
-
 	return m
 }
 
+// load picks up the config loaded at startup and reads the project store.
+func (m *model) load() error {
+	m.config = userConfig
+	if err := validateBookName(m.book); err != nil {
+		// Never write to a store outside the books directory.
+		m.storeErr = err
+		return err
+	}
+	return m.loadProjects()
+}
+
 func (m *model) loadProjects() error {
+	// A checkpoint whose change never got saved would otherwise record the
+	// reloaded changes as its own.
+	m.pendingUndo = nil
+	if m.allBooks {
+		return m.loadAllBooks()
+	}
+	m.storeErr = nil
 	data, err := os.ReadFile(m.projectsFile)
 	if err != nil {
 		if os.IsNotExist(err) {
-			m.projects = []Project{}
+			m.projects = m.withLayers([]Project{})
 			return nil
 		}
 		return err
 	}
 
-	if err := json.Unmarshal(data, &m.projects); err != nil {
+	projects, version, err := decodeStore(data)
+	if err == nil {
+		projects, err = migrate(projects, version)
+	}
+	if errors.Is(err, errNewerStore) {
+		m.projects = []Project{}
+		m.storeErr = err
 		return err
 	}
-
-	sort.Slice(m.projects, func(i, j int) bool {
-		return m.projects[i].UpdatedAt.After(m.projects[j].UpdatedAt)
-	})
-
+	if err != nil {
+		backup, _ := latestValidBackup(m.projectsFile)
+		m.projects = []Project{}
+		m.storeErr = &corruptStoreError{err: err, backup: backup}
+		return m.storeErr
+	}
+	projects = assignIDs(projects)
+	m.base = cloneProjects(projects)
+	m.disk = newFileState(m.projectsFile, data)
+	m.projects = m.withLayers(projects)
+	m.checkHealth()
+
+	if version < storeVersion {
+		return m.migrateStore(data, version)
+	}
 	return nil
 }
 
+// saveProjects writes the store and, when sync is set up, commits it.
 func (m *model) saveProjects() error {
-	data, err := json.MarshalIndent(m.projects, "", "  ")
+	// Taken before the write folds in other processes' changes, and only
+	// recorded once the change is on disk.
+	e, changed := m.takeUndo()
+	if err := m.writeProjects(); err != nil {
+		return err
+	}
+	if changed {
+		m.recordUndo(e)
+	}
+	// Committed after the store lock is released; sync takes the locks the
+	// other way round.
+	return commitStore(m.projectsFile, m.book)
+}
+
+// writeProjects writes the projects to the store, folding in changes other
+// processes made since we loaded it.
+func (m *model) writeProjects() error {
+	if m.allBooks {
+		return errReadOnly()
+	}
+	if m.storeErr != nil {
+		// Never overwrite a store we couldn't read.
+		return m.storeErr
+	}
+
+	unlock, err := lockFile(m.projectsFile)
+	if err != nil {
+		return fmt.Errorf("locking projects: %w", err)
+	}
+	defer unlock()
+
+	// Shared projects belong to their layer and are never written here.
+	local, shared := splitShared(m.projects)
+
+	// Another process may have saved since we loaded; fold its changes in
+	// rather than overwriting them.
+	current, err := os.ReadFile(m.projectsFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	onDisk := m.base
+	if err == nil && sha256.Sum256(current) != m.disk.sum {
+		theirs, err := parseProjects(current)
+		if err != nil {
+			return fmt.Errorf("projects file changed on disk and can't be read: %w", err)
+		}
+		onDisk = theirs
+		merged := model{projects: mergeProjects(m.base, local, theirs)}
+		// Both sides may have pinned or unpinned, leaving gaps or ties.
+		merged.renumberPins(merged.pinOrder())
+		local = merged.projects
+	}
+
+	data, err := encodeStore(local)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(m.projectsFile, data, 0o644)
+	// Opening projects would otherwise push every real edit out of the
+	// backups within a few opens.
+	if !onlyOpensChanged(onDisk, local) {
+		if err := rotateBackups(m.projectsFile, maxBackups); err != nil {
+			return fmt.Errorf("backing up projects: %w", err)
+		}
+	}
+	if err := writeFileAtomic(m.projectsFile, data, 0o644); err != nil {
+		return err
+	}
+	m.base = cloneProjects(local)
+	m.disk = newFileState(m.projectsFile, data)
+	m.projects = append(local, unshadowed(local, shared)...)
+	m.checkHealth()
+	return nil
+}
+
+// reloadKeepingSelection reloads the store and keeps the cursor on the same
+// project if it still exists.
+func (m *model) reloadKeepingSelection() error {
+	selected := m.selectedKey()
+	if err := m.loadProjects(); err != nil {
+		return err
+	}
+	m.applyFilter(m.textInput.Value())
+	m.selectKey(selected)
+	return nil
+}
+
+// selectedKey returns the projectKey of the selected project, or "".
+func (m *model) selectedKey() string {
+	if len(m.filteredIdxs) == 0 {
+		return ""
+	}
+	return projectKey(m.projects[m.filteredIdxs[m.cursor]])
+}
+
+// indexOfKey returns the index of the project with the given key, or -1.
+func (m *model) indexOfKey(key string) int {
+	for i, p := range m.projects {
+		if projectKey(p) == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// selectKey moves the cursor to the project with the given key, if visible.
+func (m *model) selectKey(key string) {
+	for i, idx := range m.filteredIdxs {
+		if projectKey(m.projects[idx]) == key {
+			m.cursor = i
+			m.loadSelectedToViewport()
+			return
+		}
+	}
+}
+
+// recoverFromBackup restores the store from backup and reloads it.
+func (m *model) recoverFromBackup(backup string) error {
+	if err := restoreBackup(m.projectsFile, backup); err != nil {
+		return err
+	}
+	return m.loadProjects()
 }
 
 func (m *model) addProject(p Project) error {
-	p.CreatedAt = time.Now()
+	return m.addProjects([]Project{p})
+}
+
+// addProjects adds several projects with a single save.
+func (m *model) addProjects(ps []Project) error {
+	added := make([]Project, len(ps))
+	var prev time.Time
+	for i, p := range ps {
+		if p.CreatedAt.IsZero() {
+			// Keep creation times unique even when the clock doesn't
+			// advance between entries, so the list has a stable order.
+			// Imports keep the creation time they bring.
+			p.CreatedAt = time.Now()
+			if !p.CreatedAt.After(prev) {
+				p.CreatedAt = prev.Add(time.Nanosecond)
+			}
+			prev = p.CreatedAt
+		}
+		if p.ID == "" || m.hasID(p.ID) {
+			// Imports keep their ID unless it is already taken here.
+			p.ID = newID(p.CreatedAt)
+		}
+		if p.UpdatedAt.IsZero() {
+			// Imports may bring their own edit time.
+			p.UpdatedAt = p.CreatedAt
+		}
+		if p.Remote == "" {
+			p.Remote = repoRemote(expandPath(p.Path))
+		}
+		added[i] = p
+	}
+	m.projects = append(added, m.projects...)
+	// Imported projects may carry pin positions that clash with ours.
+	m.renumberPins(m.pinOrder())
+	return m.saveProjects()
+}
+
+// markOpened records that the project at idx was just opened.
+func (m *model) markOpened(idx int) error {
+	now := time.Now()
+	m.projects[idx].recordOpen(now)
+	p := m.projects[idx]
+	if m.allBooks {
+		return recordOpenInBook(p.Book, projectKey(p), now)
+	}
+	if p.Shared != "" {
+		return recordSharedOpen(m.projectsFile, projectKey(p), now)
+	}
+	return m.saveProjects()
+}
+
+// openProject records an open of the project at idx and opens it. Failing
+// to record the open doesn't keep the project from opening; the error is
+// shown once the opener has started.
+func (m *model) openProject(idx int) tea.Cmd {
+	p := m.projects[idx]
+	m.openErr = m.markOpened(idx)
+	m.statusMessage = fmt.Sprintf("Opening '%s'...", p.Name)
+	m.isError = false
+	return openProjectCmd(p, m.config)
+}
+
+// pick selects the project at idx in pick mode and ends the program.
+func (m *model) pick(idx int) tea.Cmd {
+	m.picked = m.projects[idx].Path
+	m.openErr = m.markOpened(idx)
+	return tea.Quit
+}
+
+// updateProject replaces the project at idx, keeping its creation time.
+func (m *model) updateProject(idx int, p Project) error {
+	if idx < 0 || idx >= len(m.projects) {
+		return fmt.Errorf("invalid index")
+	}
+	if err := m.guardShared(idx); err != nil {
+		return err
+	}
+	p.ID = m.projects[idx].ID
+	p.CreatedAt = m.projects[idx].CreatedAt
 	p.UpdatedAt = time.Now()
-	m.projects = append([]Project{p}, m.projects...)
+	m.projects[idx] = p
 	return m.saveProjects()
 }
 
+// deleteProject moves the project at idx to the trash; see purgeProject.
 func (m *model) deleteProject(idx int) error {
 	if idx < 0 || idx >= len(m.projects) {
 		return fmt.Errorf("invalid index")
 	}
-	m.projects = append(m.projects[:idx], m.projects[idx+1:]...)
+	if err := m.guardShared(idx); err != nil {
+		return err
+	}
+	m.trashProject(idx)
 	return m.saveProjects()
 }
 
@@ -360,43 +773,41 @@ func fuzzyScore(query, target string) int {
 
 func (m *model) applyFilter(q string) {
 	m.filterQuery = q
+	m.highlight = highlightTerm(parseQuery(q))
 	q = strings.TrimSpace(q)
+	now := time.Now()
 
 	if q == "" {
-		// No filter, show all projects sorted by UpdatedAt
+		// No filter, show all projects ranked by frecency
 		m.filteredIdxs = m.filteredIdxs[:0]
-		for i := range m.projects {
-			m.filteredIdxs = append(m.filteredIdxs, i)
+		for i, p := range m.projects {
+			if !p.archived() {
+				m.filteredIdxs = append(m.filteredIdxs, i)
+			}
 		}
+		sort.SliceStable(m.filteredIdxs, func(i, j int) bool {
+			return rankedBefore(m.projects[m.filteredIdxs[i]], m.projects[m.filteredIdxs[j]], now)
+		})
 	} else {
-		// Fuzzy match and score
+		// Every term must match; free text is scored with fuzzyScore
+		terms := parseQuery(q)
+		withArchived := wantsArchived(terms)
 		var matches []fuzzyMatch
 		for i, p := range m.projects {
-			// Calculate score from all searchable fields
-			nameScore := fuzzyScore(q, p.Name)
-			tagScore := fuzzyScore(q, p.Tag)
-			descScore := fuzzyScore(q, p.Description)
-			pathScore := fuzzyScore(q, p.Path) / 2 // Lower weight for path
-
-			maxScore := nameScore
-			if tagScore > maxScore {
-				maxScore = tagScore
+			if p.archived() && !withArchived {
+				continue
 			}
-			if descScore > maxScore {
-				maxScore = descScore
-			}
-			if pathScore > maxScore {
-				maxScore = pathScore
-			}
-
-			if maxScore > 0 {
-				matches = append(matches, fuzzyMatch{index: i, score: maxScore})
+			if score, ok := matchQuery(terms, p); ok {
+				matches = append(matches, fuzzyMatch{index: i, score: score})
 			}
 		}
 
-		// Sort by score descending
-		sort.Slice(matches, func(i, j int) bool {
-			return matches[i].score > matches[j].score
+		// Sort by score descending, breaking ties by frecency
+		sort.SliceStable(matches, func(i, j int) bool {
+			if matches[i].score != matches[j].score {
+				return matches[i].score > matches[j].score
+			}
+			return rankedBefore(m.projects[matches[i].index], m.projects[matches[j].index], now)
 		})
 
 		// Extract indices
@@ -412,7 +823,7 @@ func (m *model) applyFilter(q string) {
 			Foreground(mutedColor).
 			Italic(true).
 			Align(lipgloss.Center).
-			Render("✨ No projects match your search\n\nTry a different query or press 'a' to add a new project"))
+			Render(fmt.Sprintf("✨ No projects match your search\n\nTry a different query or press '%s' to add a new project", keyHint(keymap.List.Add))))
 		return
 	}
 
@@ -428,7 +839,7 @@ func (m *model) loadSelectedToViewport() {
 			Foreground(mutedColor).
 			Italic(true).
 			Align(lipgloss.Center).
-			Render("No projects available\n\nPress 'a' to add your first project"))
+			Render(fmt.Sprintf("No projects available\n\nPress '%s' to add your first project", keyHint(keymap.List.Add))))
 		return
 	}
 	idx := m.filteredIdxs[m.cursor]
@@ -441,14 +852,23 @@ func (m *model) loadSelectedToViewport() {
 	content.WriteString(detailValueStyle.Render(p.Name) + "\n")
 	content.WriteString(dividerStyle.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━") + "\n")
 
-	if p.Tag != "" {
-		content.WriteString(detailLabelStyle.Render("  Tag") + "\n")
-		content.WriteString(tagStyle.Render("# "+p.Tag) + "\n")
+	if len(p.Tags) > 0 {
+		content.WriteString(detailLabelStyle.Render("  Tags") + "\n")
+		content.WriteString(renderTags(p.Tags, "") + "\n")
 		content.WriteString(dividerStyle.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━") + "\n")
 	}
 
 	content.WriteString(detailLabelStyle.Render(" Path") + "\n")
 	content.WriteString(pathStyle.Render(p.Path) + "\n")
+	if m.missing[p.Path] {
+		content.WriteString(warningStyle.Render(fmt.Sprintf("⚠ Directory not found — press %s to locate it", keyHint(keymap.List.Relocate))) + "\n")
+	}
+	if m.duplicate[p.Path] {
+		content.WriteString(warningStyle.Render(fmt.Sprintf("⧉ Registered more than once — press %s to merge", keyHint(keymap.List.Merge))) + "\n")
+	}
+	if parent, ok := m.nested[p.Path]; ok {
+		content.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render("Inside '"+parent+"'") + "\n")
+	}
 	content.WriteString(dividerStyle.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━") + "\n")
 
 	if p.Description != "" {
@@ -457,11 +877,45 @@ func (m *model) loadSelectedToViewport() {
 		content.WriteString(dividerStyle.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━") + "\n")
 	}
 
+	if m.missing[p.Path] {
+		// Nothing to report for a directory that isn't there.
+	} else if info, ok := m.gitCache[p.Path]; ok {
+		if info.isRepo || info.err != nil {
+			content.WriteString(detailLabelStyle.Render(" Git") + "\n")
+			content.WriteString(renderGitInfo(info))
+			content.WriteString(dividerStyle.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━") + "\n")
+		}
+	} else {
+		content.WriteString(detailLabelStyle.Render(" Git") + "\n")
+		content.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Italic(true).Render("loading…") + "\n")
+		content.WriteString(dividerStyle.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━") + "\n")
+	}
+
+	if p.Shared != "" {
+		content.WriteString(detailLabelStyle.Render(" Shared") + "\n")
+		content.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render("Read-only, from "+p.Shared) + "\n")
+		content.WriteString(dividerStyle.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━") + "\n")
+	}
+
+	if m.allBooks {
+		content.WriteString(detailLabelStyle.Render(" Book") + "\n")
+		content.WriteString(detailValueStyle.Render(p.Book) + "\n")
+		content.WriteString(dividerStyle.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━") + "\n")
+	}
+
+	content.WriteString(detailLabelStyle.Render(" Opens With") + "\n")
+	content.WriteString(detailValueStyle.Render(resolveOpener(p, m.config)) + "\n")
+	content.WriteString(dividerStyle.Render("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━") + "\n")
+
 	content.WriteString(detailLabelStyle.Render(" Timeline") + "\n")
-	content.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render(
-		fmt.Sprintf("Created:  %s\nModified: %s",
-			p.CreatedAt.Format("Jan 02, 2006 15:04"),
-			p.UpdatedAt.Format("Jan 02, 2006 15:04"))))
+	timeline := fmt.Sprintf("Created:  %s\nModified: %s",
+		p.CreatedAt.Format("Jan 02, 2006 15:04"),
+		p.UpdatedAt.Format("Jan 02, 2006 15:04"))
+	if p.OpenCount > 0 {
+		timeline += fmt.Sprintf("\nOpened:   %s (%d times)",
+			p.lastOpened().Format("Jan 02, 2006 15:04"), p.OpenCount)
+	}
+	content.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render(timeline))
 
 	m.viewport.SetContent(content.String())
 }
@@ -611,27 +1065,187 @@ func longestCommonPrefix(strs []string) string {
 	return prefix
 }
 
+// detectTags starts stack detection for the form's path once it validates,
+// unless the user has already entered their own tags.
+func (m *model) detectTags() tea.Cmd {
+	path := strings.TrimSpace(m.addInputs[1].Value())
+	current := m.addInputs[2].Value()
+	if path == "" || validatePath(path) != "" || (current != "" && current != m.autoTags) {
+		return nil
+	}
+	return detectTagsCmd(path)
+}
+
+// resetForm clears the add/edit form and returns to the list view.
+func (m *model) resetForm() {
+	m.mode = viewList
+	m.autoTags = ""
+	m.pathValidation = ""
+	m.autocompleteOpts = nil
+	for i := range m.addInputs {
+		m.addInputs[i].SetValue("")
+		m.addInputs[i].Blur()
+	}
+	m.addFocusIndex = 0
+}
+
 func (m model) Init() tea.Cmd {
-	return nil
+	return tea.Batch(watchStore(), m.refreshGitStatus())
 }
 
+// Update handles msg and then makes sure the selected project's git status is
+// being fetched, so every navigation path gets it without blocking.
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.update(msg)
+	return next, tea.Batch(cmd, next.(model).refreshGitStatus())
+}
+
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case gitStatusMsg:
+		delete(m.gitPending, msg.path)
+		m.gitCache[msg.path] = msg.info
+		m.recordRemote(msg.path, primaryRemote(msg.info.remotes))
+		if len(m.filteredIdxs) > 0 && m.projects[m.filteredIdxs[m.cursor]].Path == msg.path {
+			m.loadSelectedToViewport()
+		}
+		return m, nil
+
+	case relocateMsg:
+		m.handleRelocate(msg)
+		return m, nil
+
 	case editorFinishedMsg:
+		openErr := m.openErr
+		m.openErr = nil
 		if msg.err != nil {
 			m.statusMessage = fmt.Sprintf("Error: %v", msg.err)
 			m.isError = true
+		} else if openErr != nil {
+			m.statusMessage = fmt.Sprintf("Error: opened, but the open wasn't recorded: %v", openErr)
+			m.isError = true
+		} else if msg.detached {
+			m.statusMessage = "✓ Launched editor"
+			m.isError = false
 		} else {
 			m.statusMessage = "✓ Returned from editor"
 			m.isError = false
 		}
 		return m, nil
 
+	case tagsDetectedMsg:
+		if m.mode != viewAdd && m.mode != viewEdit {
+			return m, nil
+		}
+		current := m.addInputs[2].Value()
+		if strings.TrimSpace(m.addInputs[1].Value()) != msg.path || (current != "" && current != m.autoTags) {
+			// Path moved on, or the user typed their own tags.
+			return m, nil
+		}
+		m.autoTags = strings.Join(msg.tags, ", ")
+		m.addInputs[2].SetValue(m.autoTags)
+		return m, nil
+
+	case scanDoneMsg:
+		if m.mode != viewScan {
+			return m, nil
+		}
+		m.scanning = false
+		if msg.err != nil {
+			m.statusMessage = fmt.Sprintf("Scan failed: %v", msg.err)
+			m.isError = true
+			return m, nil
+		}
+		m.scanResults = msg.candidates
+		m.scanCursor = 0
+		return m, nil
+
+	case storeTickMsg:
+		if m.changedOnDisk() {
+			return m, tea.Batch(watchStore(), func() tea.Msg { return storeChangedMsg{} })
+		}
+		return m, watchStore()
+
+	case storeChangedMsg:
+		if m.mode != viewList || m.confirm != nil {
+			// Don't pull projects out from under an open form or prompt;
+			// the next tick will notice the change again.
+			return m, nil
+		}
+		if err := m.reloadKeepingSelection(); err != nil {
+			m.statusMessage = fmt.Sprintf("Error: %v", err)
+			m.isError = true
+		} else {
+			m.statusMessage = "↻ Projects changed on disk, reloaded"
+			m.isError = false
+		}
+		return m, nil
+
 	case tea.KeyMsg:
 		k := msg.String()
 
-		if m.mode == viewAdd {
-			if k == "tab" && m.addFocusIndex == 1 {
+		if m.showHelp {
+			m.showHelp = false
+			if k == quitKey {
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+
+		if m.confirm != nil {
+			prompt := m.confirm
+			m.confirm = nil
+			if k != "y" && k != "Y" {
+				m.statusMessage = "Cancelled"
+				m.isError = false
+				return m, nil
+			}
+			status, err := prompt.onYes(&m)
+			if err != nil {
+				m.statusMessage = fmt.Sprintf("Error: %v", err)
+				m.isError = true
+			} else {
+				m.statusMessage = status
+				m.isError = false
+			}
+			m.applyFilter(m.textInput.Value())
+			return m, nil
+		}
+
+		if m.exporting {
+			m.exporting = false
+			format, ok := exportKeys[k]
+			if !ok {
+				m.statusMessage = "Cancelled"
+				m.isError = false
+				return m, nil
+			}
+			status, err := m.exportFiltered(format)
+			if err != nil {
+				m.statusMessage = fmt.Sprintf("Error: %v", err)
+				m.isError = true
+			} else {
+				m.statusMessage = status
+				m.isError = false
+			}
+			return m, nil
+		}
+
+		if m.mode == viewScan {
+			return m.updateScan(msg)
+		}
+
+		if m.mode == viewTrash {
+			return m.updateTrash(msg)
+		}
+
+		if m.mode == viewBooks {
+			return m.updateBooks(msg)
+		}
+
+		if m.mode == viewAdd || m.mode == viewEdit {
+			fk := keymap.Form
+			if key.Matches(msg, fk.Complete) && m.addFocusIndex == 1 {
 				currentPath := m.addInputs[1].Value()
 				completed, matches := autocomplete(currentPath)
 
@@ -642,6 +1256,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					if len(matches) == 0 {
 						m.autocompleteOpts = nil
 						m.pathValidation = validatePath(completed)
+						return m, m.detectTags()
 					} else {
 						m.autocompleteOpts = matches
 					}
@@ -652,19 +1267,13 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, nil
 			}
 
-			switch k {
-			case "esc":
-				m.mode = viewList
+			switch {
+			case key.Matches(msg, fk.Cancel):
+				m.resetForm()
 				m.statusMessage = "Cancelled"
 				m.isError = false
-				m.pathValidation = ""
-				m.autocompleteOpts = nil
-				for i := range m.addInputs {
-					m.addInputs[i].SetValue("")
-				}
-				m.addFocusIndex = 0
 				return m, nil
-			case "shift+tab", "up":
+			case key.Matches(msg, fk.Prev):
 				m.addFocusIndex--
 				if m.addFocusIndex < 0 {
 					m.addFocusIndex = len(m.addInputs)
@@ -678,7 +1287,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				m.autocompleteOpts = nil
 				return m, nil
-			case "down":
+			case key.Matches(msg, fk.Next, fk.Complete):
 				if len(m.autocompleteOpts) == 0 {
 					m.addFocusIndex++
 					if m.addFocusIndex > len(m.addInputs) {
@@ -693,12 +1302,13 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 				}
 				return m, nil
-			case "enter":
+			case key.Matches(msg, fk.Submit):
 				if m.addFocusIndex == len(m.addInputs) {
 					name := strings.TrimSpace(m.addInputs[0].Value())
 					path := strings.TrimSpace(m.addInputs[1].Value())
-					tag := strings.TrimSpace(m.addInputs[2].Value())
+					tags := splitTags(m.addInputs[2].Value())
 					desc := strings.TrimSpace(m.addInputs[3].Value())
+					opener := strings.TrimSpace(m.addInputs[4].Value())
 
 					if name == "" || path == "" {
 						m.statusMessage = "Name and Path are required!"
@@ -708,35 +1318,73 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 					validation := validatePath(path)
 					if validation != "" {
-						m.statusMessage = "Cannot add project: " + strings.TrimPrefix(validation, "⚠ ")
+						verb := "add"
+						if m.mode == viewEdit {
+							verb = "save"
+						}
+						m.statusMessage = fmt.Sprintf("Cannot %s project: %s", verb, strings.TrimPrefix(validation, "⚠ "))
 						m.isError = true
 						m.pathValidation = validation
 						return m, nil
 					}
 
-					path = expandPath(path)
+					path = normalizePath(path)
+					// Look the project up again; the store may have been
+					// reloaded or merged while the form was open.
+					editIdx := -1
+					if m.mode == viewEdit {
+						if editIdx = m.indexOfKey(m.editKey); editIdx < 0 {
+							m.statusMessage = fmt.Sprintf("'%s' no longer exists", name)
+							m.isError = true
+							return m, nil
+						}
+					}
+					if dup := m.findByPath(path, editIdx); dup >= 0 {
+						m.statusMessage = fmt.Sprintf("'%s' already points to %s", m.projects[dup].Name, path)
+						m.isError = true
+						return m, nil
+					}
 
-					project := Project{
-						Name:        name,
-						Path:        path,
-						Tag:         tag,
-						Description: desc,
+					// Editing keeps everything the form doesn't show, such as
+					// the open history and pin position.
+					var project Project
+					if m.mode == viewEdit {
+						project = m.projects[editIdx]
+					}
+					project.Name = name
+					project.Path = path
+					project.Tags = tags
+					project.Description = desc
+					project.Opener = opener
+
+					if m.mode == viewEdit {
+						m.checkpoint(fmt.Sprintf("edit '%s'", m.projects[editIdx].Name))
+						if err := m.updateProject(editIdx, project); err != nil {
+							m.dropCheckpoint()
+							m.statusMessage = fmt.Sprintf("Error: %v", err)
+							m.isError = true
+						} else {
+							m.statusMessage = fmt.Sprintf("✓ Updated '%s'", name)
+							m.isError = false
+							m.resetForm()
+							m.applyFilter(m.textInput.Value())
+						}
+						return m, nil
 					}
 
+					m.checkpoint(fmt.Sprintf("add '%s'", name))
 					if err := m.addProject(project); err != nil {
+						m.dropCheckpoint()
 						m.statusMessage = fmt.Sprintf("Error: %v", err)
 						m.isError = true
 					} else {
 						m.statusMessage = fmt.Sprintf("✓ Added '%s'", name)
+						if parent, ok := m.nested[path]; ok {
+							m.statusMessage += fmt.Sprintf(" (inside '%s')", parent)
+						}
 						m.isError = false
 						m.applyFilter("")
-						m.mode = viewList
-						m.pathValidation = ""
-						m.autocompleteOpts = nil
-						for i := range m.addInputs {
-							m.addInputs[i].SetValue("")
-						}
-						m.addFocusIndex = 0
+						m.resetForm()
 					}
 					return m, nil
 				} else {
@@ -766,6 +1414,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					if newValue != oldValue {
 						m.pathValidation = validatePath(newValue)
 						m.autocompleteOpts = nil
+						cmd = tea.Batch(cmd, m.detectTags())
 					}
 				}
 
@@ -775,16 +1424,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		if m.filterMode {
-			switch k {
-			case "esc":
+			fk := keymap.Filter
+			switch {
+			case key.Matches(msg, fk.Clear):
 				m.filterMode = false
 				m.textInput.Blur()
 				m.textInput.SetValue("")
 				m.applyFilter("")
 				m.statusMessage = ""
 				return m, nil
-			case "enter":
-				// Open the selected project with Enter
+			case key.Matches(msg, fk.Open):
+				// Open the selected project
 				if len(m.filteredIdxs) == 0 {
 					m.statusMessage = "No project to open"
 					m.isError = true
@@ -793,27 +1443,25 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.filterMode = false
 				m.textInput.Blur()
 				idx := m.filteredIdxs[m.cursor]
-				path := m.projects[idx].Path
-				m.projects[idx].UpdatedAt = time.Now()
-				m.saveProjects()
-				m.statusMessage = fmt.Sprintf("Opening '%s'...", m.projects[idx].Name)
-				m.isError = false
-				return m, openProjectCmd(path)
-			case "down", "ctrl+n":
+				if m.pickMode {
+					return m, m.pick(idx)
+				}
+				return m, m.openProject(idx)
+			case key.Matches(msg, fk.Down):
 				// Navigate down while filtering
 				if len(m.filteredIdxs) > 0 {
 					m.cursor = (m.cursor + 1) % len(m.filteredIdxs)
 					m.loadSelectedToViewport()
 				}
 				return m, nil
-			case "up", "ctrl+p":
+			case key.Matches(msg, fk.Up):
 				// Navigate up while filtering
 				if len(m.filteredIdxs) > 0 {
 					m.cursor = (m.cursor - 1 + len(m.filteredIdxs)) % len(m.filteredIdxs)
 					m.loadSelectedToViewport()
 				}
 				return m, nil
-			case "ctrl+c":
+			case k == quitKey:
 				return m, tea.Quit
 			default:
 				var cmd tea.Cmd
@@ -829,10 +1477,37 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, cmd
 			}
 		}
-		switch k {
-		case "q", "ctrl+c":
+		lk := keymap.List
+		if m.allBooks && key.Matches(msg, editKeys()...) {
+			m.statusMessage = errReadOnly().Error()
+			m.isError = true
+			return m, nil
+		}
+		if key.Matches(msg, sharedKeys()...) && len(m.filteredIdxs) > 0 {
+			if err := m.guardShared(m.filteredIdxs[m.cursor]); err != nil {
+				m.statusMessage = err.Error()
+				m.isError = true
+				return m, nil
+			}
+		}
+		switch {
+		case k == quitKey, key.Matches(msg, lk.Quit):
 			return m, tea.Quit
-		case "a":
+		case key.Matches(msg, lk.Help):
+			m.showHelp = true
+			return m, nil
+		case key.Matches(msg, lk.Books):
+			m.openBookPicker()
+			return m, nil
+		case key.Matches(msg, lk.Scan):
+			return m, m.startScan()
+		case key.Matches(msg, lk.Trash):
+			m.mode = viewTrash
+			m.trashCursor = 0
+			m.statusMessage = ""
+			m.refreshTrash()
+			return m, nil
+		case key.Matches(msg, lk.Add):
 			m.mode = viewAdd
 			m.addFocusIndex = 0
 			m.addInputs[0].Focus()
@@ -843,26 +1518,51 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.pathValidation = ""
 			m.autocompleteOpts = nil
 			return m, nil
-		case "/":
+		case key.Matches(msg, lk.Edit):
+			if len(m.filteredIdxs) == 0 {
+				m.statusMessage = "No project to edit"
+				m.isError = true
+				return m, nil
+			}
+			idx := m.filteredIdxs[m.cursor]
+			p := m.projects[idx]
+			m.mode = viewEdit
+			m.editKey = projectKey(p)
+			m.addInputs[0].SetValue(p.Name)
+			m.addInputs[1].SetValue(p.Path)
+			m.addInputs[2].SetValue(strings.Join(p.Tags, ", "))
+			m.addInputs[3].SetValue(p.Description)
+			m.addInputs[4].SetValue(p.Opener)
+			m.addFocusIndex = 0
+			m.addInputs[0].Focus()
+			m.addInputs[0].CursorEnd()
+			for i := 1; i < len(m.addInputs); i++ {
+				m.addInputs[i].Blur()
+			}
+			m.statusMessage = ""
+			m.pathValidation = validatePath(p.Path)
+			m.autocompleteOpts = nil
+			return m, nil
+		case key.Matches(msg, lk.Search):
 			m.filterMode = true
 			m.textInput.Focus()
 			m.statusMessage = ""
 			return m, nil
-		case "j", "down":
+		case key.Matches(msg, lk.Down):
 			if len(m.filteredIdxs) > 0 {
 				m.cursor = (m.cursor + 1) % len(m.filteredIdxs)
 				m.loadSelectedToViewport()
 				m.statusMessage = ""
 			}
 			return m, nil
-		case "k", "up":
+		case key.Matches(msg, lk.Up):
 			if len(m.filteredIdxs) > 0 {
 				m.cursor = (m.cursor - 1 + len(m.filteredIdxs)) % len(m.filteredIdxs)
 				m.loadSelectedToViewport()
 				m.statusMessage = ""
 			}
 			return m, nil
-		case "d":
+		case key.Matches(msg, lk.Delete):
 			if len(m.filteredIdxs) == 0 {
 				m.statusMessage = "No project to delete"
 				m.isError = true
@@ -870,29 +1570,152 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			idx := m.filteredIdxs[m.cursor]
 			name := m.projects[idx].Name
-			if err := m.deleteProject(idx); err != nil {
+			key := projectKey(m.projects[idx])
+			del := func(m *model) (string, error) {
+				// Look the project up again; the store may have been reloaded.
+				for i, p := range m.projects {
+					if projectKey(p) == key {
+						m.checkpoint(fmt.Sprintf("delete '%s'", name))
+						if err := m.deleteProject(i); err != nil {
+							m.dropCheckpoint()
+							return "", err
+						}
+						return fmt.Sprintf("✓ Moved '%s' to trash (%s to undo, %s to view)", name, keyHint(keymap.List.Undo), keyHint(keymap.List.Trash)), nil
+					}
+				}
+				return "", fmt.Errorf("'%s' no longer exists", name)
+			}
+			if m.config.confirmDelete() {
+				m.confirm = &confirmPrompt{question: fmt.Sprintf("Delete '%s'?", name), onYes: del}
+				return m, nil
+			}
+			status, err := del(&m)
+			if err != nil {
 				m.statusMessage = fmt.Sprintf("Error: %v", err)
 				m.isError = true
 			} else {
-				m.statusMessage = fmt.Sprintf("✓ Deleted '%s'", name)
+				m.statusMessage = status
 				m.isError = false
 				m.applyFilter(m.textInput.Value())
 			}
 			return m, nil
-		case "o", "enter":
+		case key.Matches(msg, lk.Undo, lk.Redo):
+			selected := m.selectedKey()
+			var status string
+			var err error
+			if key.Matches(msg, lk.Undo) {
+				status, err = m.undo()
+			} else {
+				status, err = m.redo()
+			}
+			if err != nil {
+				m.statusMessage = err.Error()
+				m.isError = true
+				return m, nil
+			}
+			m.statusMessage = status
+			m.isError = false
+			m.applyFilter(m.textInput.Value())
+			m.selectKey(selected)
+			return m, nil
+		case key.Matches(msg, lk.Pin, lk.PinUp, lk.PinDown):
+			if len(m.filteredIdxs) == 0 {
+				return m, nil
+			}
+			idx := m.filteredIdxs[m.cursor]
+			p := m.projects[idx]
+			selected := projectKey(p)
+			var err error
+			switch {
+			case key.Matches(msg, lk.Pin) && p.Pinned > 0:
+				m.checkpoint(fmt.Sprintf("unpin '%s'", p.Name))
+				err = m.togglePin(idx)
+				m.statusMessage = fmt.Sprintf("✓ Unpinned '%s'", p.Name)
+			case key.Matches(msg, lk.Pin):
+				m.checkpoint(fmt.Sprintf("pin '%s'", p.Name))
+				err = m.togglePin(idx)
+				m.statusMessage = fmt.Sprintf("✓ Pinned '%s'", p.Name)
+			case p.Pinned == 0:
+				err = fmt.Errorf("only pinned projects can be reordered (press %s to pin)", keyHint(lk.Pin))
+			default:
+				delta := 1
+				if key.Matches(msg, lk.PinUp) {
+					delta = -1
+				}
+				if target := m.pinPosition(idx) + delta; target < 0 || target >= len(m.pinOrder()) {
+					return m, nil
+				}
+				m.checkpoint(fmt.Sprintf("move '%s'", p.Name))
+				err = m.movePin(idx, delta)
+				m.statusMessage = ""
+			}
+			if err != nil {
+				m.dropCheckpoint()
+				m.statusMessage = fmt.Sprintf("Error: %v", err)
+				m.isError = true
+				return m, nil
+			}
+			m.isError = false
+			m.applyFilter(m.textInput.Value())
+			m.selectKey(selected)
+			return m, nil
+		case key.Matches(msg, lk.Open, lk.Select):
 			if len(m.filteredIdxs) == 0 {
 				m.statusMessage = "No project to open"
 				m.isError = true
 				return m, nil
 			}
 			idx := m.filteredIdxs[m.cursor]
-			path := m.projects[idx].Path
-			m.projects[idx].UpdatedAt = time.Now()
-			m.saveProjects()
-			m.statusMessage = fmt.Sprintf("Opening '%s'...", m.projects[idx].Name)
+			if m.pickMode && key.Matches(msg, lk.Select) {
+				return m, m.pick(idx)
+			}
+			p := m.projects[idx]
+			if m.missing[p.Path] && p.Shared != "" {
+				m.statusMessage = fmt.Sprintf("'%s' is not at %s; set path_overrides in %s", p.Name, p.Path, filepath.Base(configPath()))
+				m.isError = true
+				return m, nil
+			}
+			if m.missing[p.Path] {
+				m.statusMessage = fmt.Sprintf("'%s' is missing; looking for where it moved…", p.Name)
+				m.isError = true
+				return m, m.relocateCmd(p)
+			}
+			return m, m.openProject(idx)
+		case key.Matches(msg, lk.Export):
+			if len(m.filteredIdxs) == 0 {
+				m.statusMessage = "No projects to export"
+				m.isError = true
+				return m, nil
+			}
+			m.exporting = true
+			return m, nil
+		case key.Matches(msg, lk.Merge):
+			if len(m.filteredIdxs) == 0 {
+				return m, nil
+			}
+			p := m.projects[m.filteredIdxs[m.cursor]]
+			if !m.duplicate[p.Path] {
+				m.statusMessage = fmt.Sprintf("'%s' has no duplicates", p.Name)
+				m.isError = true
+				return m, nil
+			}
+			key := projectKey(p)
+			m.confirm = &confirmPrompt{
+				question: fmt.Sprintf("Merge every entry for %s into '%s'?", p.Path, p.Name),
+				onYes: func(m *model) (string, error) {
+					return m.mergeSelectedDuplicates(key)
+				},
+			}
+			return m, nil
+		case key.Matches(msg, lk.Relocate):
+			if len(m.filteredIdxs) == 0 {
+				return m, nil
+			}
+			p := m.projects[m.filteredIdxs[m.cursor]]
+			m.statusMessage = fmt.Sprintf("Looking for '%s' under the scan roots…", p.Name)
 			m.isError = false
-			return m, openProjectCmd(path)
-		case "r":
+			return m, m.relocateCmd(p)
+		case key.Matches(msg, lk.Reload):
 			if err := m.loadProjects(); err != nil {
 				m.statusMessage = fmt.Sprintf("Error: %v", err)
 				m.isError = true
@@ -905,8 +1728,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case tea.WindowSizeMsg:
+		m.help.Width = msg.Width
 		if !m.ready {
-			m.leftWidth = 45
 			rightW := msg.Width - m.leftWidth - 8
 			if rightW < 30 {
 				rightW = 30
@@ -948,9 +1771,30 @@ func (m model) View() string {
 			Render(" Loading Project Phonebook...")
 	}
 
-	if m.mode == viewAdd {
+	if m.showHelp {
+		return m.helpView()
+	}
+
+	if m.mode == viewScan {
+		return m.scanView()
+	}
+
+	if m.mode == viewTrash {
+		return m.trashView()
+	}
+
+	if m.mode == viewBooks {
+		return m.booksView()
+	}
+
+	if m.mode == viewAdd || m.mode == viewEdit {
 		var b strings.Builder
 
+		title := "✨ Add New Project"
+		if m.mode == viewEdit {
+			title = "✏ Edit Project"
+		}
+
 		header := lipgloss.NewStyle().
 			Foreground(primaryColor).
 			Bold(true).
@@ -960,11 +1804,11 @@ func (m model) View() string {
 			Width(70).
 			Border(lipgloss.RoundedBorder()).
 			BorderForeground(primaryColor).
-			Render("✨ Add New Project")
+			Render(title)
 
 		b.WriteString(header + "\n\n")
 
-		labels := []string{" Project Name", " Project Path", "  Tags", " Description"}
+		labels := []string{" Project Name", " Project Path", "  Tags", " Description", " Open With"}
 		for i, input := range m.addInputs {
 			b.WriteString(labelStyle.Render(labels[i]) + "\n")
 			b.WriteString(input.View() + "\n")
@@ -977,7 +1821,7 @@ func (m model) View() string {
 				b.WriteString(lipgloss.NewStyle().
 					Foreground(mutedColor).
 					Italic(true).
-					Render(fmt.Sprintf("   %d matches - press tab again to cycle", len(m.autocompleteOpts))) + "\n")
+					Render(fmt.Sprintf("   %d matches - press %s again to cycle", len(m.autocompleteOpts), keyHint(keymap.Form.Complete))) + "\n")
 
 				maxShow := 5
 				if len(m.autocompleteOpts) < maxShow {
@@ -1005,8 +1849,7 @@ func (m model) View() string {
 
 		b.WriteString("\n" + submitBtn + "\n\n")
 
-		helpText := helpStyle.Render("tab: autocomplete/next • shift+tab: previous • enter: submit • esc: cancel")
-		b.WriteString(helpText)
+		b.WriteString(m.helpBar(keymap.Form.shortHelp(), ""))
 
 		if m.statusMessage != "" {
 			var statusText string
@@ -1027,9 +1870,12 @@ func (m model) View() string {
 
 	// Header with counter (2 lines)
 	header := titleStyle.Render(" Project Phonebook")
-	count := counterStyle.Render(fmt.Sprintf("%d", len(m.projects)))
+	if m.allBooks || m.book != defaultBook {
+		header = titleStyle.Render(" " + m.bookLabel())
+	}
+	count := counterStyle.Render(fmt.Sprintf("%d", m.activeCount()))
 	if m.filterQuery != "" {
-		filteredCount := counterStyle.Render(fmt.Sprintf("%d/%d", len(m.filteredIdxs), len(m.projects)))
+		filteredCount := counterStyle.Render(fmt.Sprintf("%d/%d", len(m.filteredIdxs), m.activeCount()))
 		leftContent.WriteString(header + " " + filteredCount + "\n\n")
 	} else {
 		leftContent.WriteString(header + " " + count + "\n\n")
@@ -1082,14 +1928,26 @@ func (m model) View() string {
 		leftContent.WriteString(lipgloss.NewStyle().
 			Foreground(mutedColor).
 			Italic(true).
-			Render("✨ No projects match\n\nPress 'a' to add one"))
+			Render(fmt.Sprintf("✨ No projects match\n\nPress '%s' to add one", keyHint(keymap.List.Add))))
 	} else {
 		for i := startIdx; i < endIdx; i++ {
 			idx := m.filteredIdxs[i]
 			p := m.projects[idx]
 
 			var line string
-			displayName := highlightMatches(m.filterQuery, p.Name)
+			displayName := highlightMatches(m.highlight, p.Name)
+			if p.Pinned > 0 {
+				displayName = "📌 " + displayName
+			}
+			if m.missing[p.Path] {
+				displayName += " " + warningStyle.Render("⚠ missing")
+			}
+			if m.duplicate[p.Path] {
+				displayName += " " + warningStyle.Render("⧉ duplicate")
+			}
+			if p.Shared != "" {
+				displayName += " " + lipgloss.NewStyle().Foreground(mutedColor).Render("⇄ shared")
+			}
 
 			if i == m.cursor {
 				line = selectedItemStyle.Render("▶ " + displayName)
@@ -1100,12 +1958,17 @@ func (m model) View() string {
 
 			// Tag and path
 			var metadata strings.Builder
-			if p.Tag != "" {
-				highlightedTag := highlightMatches(m.filterQuery, p.Tag)
-				metadata.WriteString(tagStyle.Render(" #" + highlightedTag))
+			if len(p.Tags) > 0 {
+				metadata.WriteString(" " + renderTags(p.Tags, m.highlight))
 			}
 			metadata.WriteString("\n")
-			metadata.WriteString(pathStyle.Render("   " + truncate(p.Path, 38)))
+			if m.allBooks {
+				// Long book names get at most half the line.
+				book := truncate(p.Book, (m.leftWidth-9)/2)
+				metadata.WriteString(pathStyle.Render("   " + book + " · " + truncate(p.Path, m.leftWidth-9-len(book))))
+			} else {
+				metadata.WriteString(pathStyle.Render("   " + truncate(p.Path, m.leftWidth-7)))
+			}
 
 			leftContent.WriteString(metadata.String() + "\n\n")
 		}
@@ -1132,22 +1995,19 @@ func (m model) View() string {
 
 	combined := lipgloss.JoinHorizontal(lipgloss.Top, left, right)
 
-	// Help bar
-	helpKeys := []string{
-		helpKey("j/k", "move"),
-		helpKey("o/↵", "open"),
-		helpKey("a", "add"),
-		helpKey("d", "delete"),
-		helpKey("/", "search"),
-		helpKey("esc", "clear search"),
-		helpKey("r", "reload"),
-		helpKey("q", "quit"),
-	}
-	help := helpStyle.Render(strings.Join(helpKeys, "  •  "))
-
 	// Status message
 	status := ""
-	if m.statusMessage != "" {
+	if m.confirm != nil {
+		status = errorStyle.Render("? "+m.confirm.question) + helpStyle.Render("y/N")
+	} else if m.exporting {
+		status = errorStyle.Render(fmt.Sprintf("? Export %d projects as", len(m.filteredIdxs))) + helpStyle.Render(strings.Join([]string{
+			helpKey("c", "csv"),
+			helpKey("y", "yaml"),
+			helpKey("t", "toml"),
+			helpKey("m", "markdown"),
+			helpKey("j", "json"),
+		}, "  •  "))
+	} else if m.statusMessage != "" {
 		if m.isError {
 			status = errorStyle.Render("✗ " + m.statusMessage)
 		} else {
@@ -1155,7 +2015,13 @@ func (m model) View() string {
 		}
 	}
 
-	return combined + "\n" + help + status
+	// Help bar, for searching or the list
+	bindings := keymap.List.shortHelp(m.pickMode)
+	if m.filterMode {
+		bindings = keymap.Filter.shortHelp()
+	}
+
+	return combined + "\n" + m.helpBar(bindings, status) + status
 }
 
 func helpKey(key, desc string) string {
@@ -1167,33 +2033,67 @@ func truncate(s string, max int) string {
 	if len(s) <= max {
 		return s
 	}
+	if max < 4 {
+		// No room for the ellipsis.
+		if max < 0 {
+			max = 0
+		}
+		return s[:max]
+	}
 	return s[:max-3] + "..."
 }
 
-func openProjectCmd(path string) tea.Cmd {
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return func() tea.Msg {
-			return editorFinishedMsg{err: fmt.Errorf("path does not exist: %s", path)}
-		}
+func main() {
+	setupConfig()
+	args, err := takeBookFlag(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "phonebook: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	if len(args) > 0 && (!strings.HasPrefix(args[0], "-") || args[0] == "-h" || args[0] == "--help") {
+		os.Exit(runCLI(args))
 	}
 
-	c := exec.Command("nvim", ".")
-	c.Dir = path
-
-	return tea.ExecProcess(c, func(err error) tea.Msg {
-		return editorFinishedMsg{err: err}
-	})
-}
+	fs := flag.NewFlagSet("phonebook", flag.ExitOnError)
+	printPath := fs.Bool("print-path", false, "print the selected project's path on Enter and exit")
+	out := fs.String("out", "", "write the selected project's path to this file (implies --print-path)")
+	fs.Parse(args)
+
+	pickMode := *printPath || *out != ""
+	opts := []tea.ProgramOption{tea.WithAltScreen(), tea.WithMouseCellMotion()}
+	screen := os.Stdout
+	if pickMode && *out == "" {
+		// stdout carries the selected path, so draw the UI on stderr.
+		screen = os.Stderr
+		opts = append(opts, tea.WithOutput(os.Stderr))
+	}
+	setupTheme(screen)
 
-func main() {
-	p := tea.NewProgram(
-		initialModel(),
-		tea.WithAltScreen(),
-		tea.WithMouseCellMotion(),
-	)
+	m := initialModel()
+	m.pickMode = pickMode
+	p := tea.NewProgram(m, opts...)
 
-	if _, err := p.Run(); err != nil {
+	final, err := p.Run()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error running program: %v\n", err)
 		os.Exit(1)
 	}
+
+	if m.pickMode {
+		picked := final.(model).picked
+		if picked == "" {
+			os.Exit(exitError)
+		}
+		if err := final.(model).openErr; err != nil {
+			fmt.Fprintf(os.Stderr, "phonebook: the open wasn't recorded: %v\n", err)
+		}
+		if *out != "" {
+			if err := os.WriteFile(*out, []byte(picked+"\n"), 0o600); err != nil {
+				fmt.Fprintf(os.Stderr, "phonebook: %v\n", err)
+				os.Exit(exitError)
+			}
+			return
+		}
+		fmt.Println(picked)
+	}
 }
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultOpener is used when nothing else is configured.
+const defaultOpener = "nvim"
+
+// resolveOpener picks the command template for a project. The first non-empty
+// value wins: the project's own opener, an opener for its first tag that has one, the
+// global config value, $VISUAL, $EDITOR and finally nvim.
+func resolveOpener(p Project, cfg Config) string {
+	if p.Opener != "" {
+		return p.Opener
+	}
+	for _, t := range p.Tags {
+		if o := cfg.TagOpeners[t]; o != "" {
+			return o
+		}
+	}
+	if cfg.Opener != "" {
+		return cfg.Opener
+	}
+	if v := os.Getenv("VISUAL"); v != "" {
+		return v
+	}
+	if e := os.Getenv("EDITOR"); e != "" {
+		return e
+	}
+	return defaultOpener
+}
+
+// buildOpenCmd turns an opener template into a command running in the project
+// directory. Templates may use {path}, {name} and {tag} (the first tag); when none are present
+// "." is appended so that plain editor names open the directory. A trailing
+// "&" marks the opener as detached (e.g. GUI editors) instead of taking over
+// the terminal.
+func buildOpenCmd(p Project, tmpl string) (*exec.Cmd, bool, error) {
+	tmpl = strings.TrimSpace(tmpl)
+	detach := false
+	if strings.HasSuffix(tmpl, "&") {
+		detach = true
+		tmpl = strings.TrimSpace(strings.TrimSuffix(tmpl, "&"))
+	}
+
+	args, err := splitCommand(tmpl)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(args) == 0 {
+		return nil, false, fmt.Errorf("empty opener command")
+	}
+
+	tag := ""
+	if len(p.Tags) > 0 {
+		tag = p.Tags[0]
+	}
+	replacer := strings.NewReplacer("{path}", p.Path, "{name}", p.Name, "{tag}", tag)
+	hasPlaceholder := false
+	for i, a := range args {
+		if r := replacer.Replace(a); r != a {
+			args[i] = r
+			hasPlaceholder = true
+		}
+	}
+	if !hasPlaceholder {
+		args = append(args, ".")
+	}
+
+	c := exec.Command(args[0], args[1:]...)
+	c.Dir = p.Path
+	return c, detach, nil
+}
+
+// splitCommand splits a command line into arguments, honouring single and
+// double quotes and backslash escapes.
+func splitCommand(s string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", s)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash in %q", s)
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+// openProject runs the opener for p outside of Bubble Tea, attached to the
+// current terminal unless the opener is detached. started is called once the
+// opener is running, so that only opens that happened are recorded.
+func openProject(p Project, cfg Config, started func() error) error {
+	if _, err := os.Stat(p.Path); os.IsNotExist(err) {
+		return fmt.Errorf("path does not exist: %s\nrun 'phonebook doctor' to find where it moved", p.Path)
+	}
+
+	c, detach, err := buildOpenCmd(p, resolveOpener(p, cfg))
+	if err != nil {
+		return err
+	}
+
+	if detach {
+		if err := c.Start(); err != nil {
+			return err
+		}
+		if err := c.Process.Release(); err != nil {
+			return err
+		}
+		return started()
+	}
+
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Start(); err != nil {
+		return err
+	}
+	startedErr := started()
+	if err := c.Wait(); err != nil {
+		return err
+	}
+	return startedErr
+}
+
+func openProjectCmd(p Project, cfg Config) tea.Cmd {
+	if _, err := os.Stat(p.Path); os.IsNotExist(err) {
+		return func() tea.Msg {
+			return editorFinishedMsg{err: fmt.Errorf("path does not exist: %s", p.Path)}
+		}
+	}
+
+	c, detach, err := buildOpenCmd(p, resolveOpener(p, cfg))
+	if err != nil {
+		return func() tea.Msg {
+			return editorFinishedMsg{err: err}
+		}
+	}
+
+	if detach {
+		return func() tea.Msg {
+			if err := c.Start(); err != nil {
+				return editorFinishedMsg{err: err}
+			}
+			go c.Wait()
+			return editorFinishedMsg{detached: true}
+		}
+	}
+
+	return tea.ExecProcess(c, func(err error) tea.Msg {
+		return editorFinishedMsg{err: err}
+	})
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"   ", nil, false},
+		{"nvim", []string{"nvim"}, false},
+		{"code  --wait\t.", []string{"code", "--wait", "."}, false},
+		{`open -a "Visual Studio Code"`, []string{"open", "-a", "Visual Studio Code"}, false},
+		{`sh -c 'cd {path} && make'`, []string{"sh", "-c", "cd {path} && make"}, false},
+		{`echo ""`, []string{"echo", ""}, false},
+		{`a"b c"d`, []string{"ab cd"}, false},
+		{`my\ editor`, []string{"my editor"}, false},
+		{`"say \"hi\""`, []string{`say "hi"`}, false},
+		{`'no \escape'`, []string{`no \escape`}, false},
+		{`vim "unterminated`, nil, true},
+		{`vim 'unterminated`, nil, true},
+		{`vim \`, nil, true},
+		{`a "b 'c" 'd`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := splitCommand(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitCommand(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("splitCommand(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildOpenCmd(t *testing.T) {
+	p := Project{Name: "api", Path: "/src/my api", Tags: []string{"go", "web"}}
+	tests := []struct {
+		name       string
+		tmpl       string
+		project    Project
+		wantArgs   []string
+		wantDetach bool
+		wantErr    bool
+	}{
+		{"plain editor opens the directory", "nvim", p, []string{"nvim", "."}, false, false},
+		{"path placeholder", "code --wait {path}", p, []string{"code", "--wait", "/src/my api"}, false, false},
+		{"placeholders inside an argument", "tmux new -s {name}-{tag}", p, []string{"tmux", "new", "-s", "api-go"}, false, false},
+		{"no tags", "echo {tag}", Project{Path: "/src"}, []string{"echo", ""}, false, false},
+		{"detached", "idea {path} &", p, []string{"idea", "/src/my api"}, true, false},
+		{"detached without a space", "zed&", p, []string{"zed", "."}, true, false},
+		{"surrounding space", "  vim  ", p, []string{"vim", "."}, false, false},
+		{"empty", "", p, nil, false, true},
+		{"only &", " & ", p, nil, false, true},
+		{"bad quoting", `vim "{path}`, p, nil, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, detach, err := buildOpenCmd(tt.project, tt.tmpl)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildOpenCmd(%q) error = %v, want error %v", tt.tmpl, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !slices.Equal(c.Args, tt.wantArgs) || detach != tt.wantDetach {
+				t.Errorf("buildOpenCmd(%q) = %q, detach %v; want %q, detach %v", tt.tmpl, c.Args, detach, tt.wantArgs, tt.wantDetach)
+			}
+			if c.Dir != tt.project.Path {
+				t.Errorf("buildOpenCmd(%q) runs in %q, want %q", tt.tmpl, c.Dir, tt.project.Path)
+			}
+		})
+	}
+}
+
+func TestResolveOpener(t *testing.T) {
+	cfg := Config{Opener: "code", TagOpeners: map[string]string{"go": "goland"}}
+	tests := []struct {
+		name           string
+		project        Project
+		cfg            Config
+		visual, editor string
+		want           string
+	}{
+		{"project opener", Project{Opener: "emacs", Tags: []string{"go"}}, cfg, "vi", "vim", "emacs"},
+		{"first tag with an opener", Project{Tags: []string{"web", "go"}}, cfg, "vi", "vim", "goland"},
+		{"config opener", Project{Tags: []string{"web"}}, cfg, "vi", "vim", "code"},
+		{"VISUAL", Project{}, Config{}, "vi", "vim", "vi"},
+		{"EDITOR", Project{}, Config{}, "", "vim", "vim"},
+		{"default", Project{}, Config{}, "", "", defaultOpener},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("VISUAL", tt.visual)
+			t.Setenv("EDITOR", tt.editor)
+			if got := resolveOpener(tt.project, tt.cfg); got != tt.want {
+				t.Errorf("resolveOpener() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenRecordingFailureIsShown(t *testing.T) {
+	p := testProject("api", t0)
+	p.Path = t.TempDir()
+	m := storeWith(t, p)
+	m.storeErr = errors.New("store is unreadable")
+
+	m.openProject(0)
+	if m.projects[0].OpenCount != 1 {
+		t.Errorf("open count %d, want the open counted in memory", m.projects[0].OpenCount)
+	}
+	next, _ := m.update(editorFinishedMsg{detached: true})
+	got := next.(model)
+	if !got.isError || !strings.Contains(got.statusMessage, "store is unreadable") {
+		t.Errorf("status %q, want the error recording the open", got.statusMessage)
+	}
+	next, _ = got.update(editorFinishedMsg{detached: true})
+	if got := next.(model); got.isError {
+		t.Errorf("status %q after the next open, want the error gone", got.statusMessage)
+	}
+
+	m.pick(0)
+	if m.openErr == nil || m.picked != p.Path {
+		t.Errorf("pick() kept error %v and path %q, want the error and %q", m.openErr, m.picked, p.Path)
+	}
+}
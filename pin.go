@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// pinOrder returns the pinned projects' indices in pin order.
+func (m *model) pinOrder() []int {
+	var pinned []int
+	for i, p := range m.projects {
+		if p.Pinned > 0 {
+			pinned = append(pinned, i)
+		}
+	}
+	sort.SliceStable(pinned, func(i, j int) bool {
+		return m.projects[pinned[i]].Pinned < m.projects[pinned[j]].Pinned
+	})
+	return pinned
+}
+
+// pinPosition returns where the project at idx sits in pinOrder, or -1 if it
+// isn't pinned. Pin numbers can have gaps after a merge, so this is not
+// always Pinned-1.
+func (m *model) pinPosition(idx int) int {
+	for pos, i := range m.pinOrder() {
+		if i == idx {
+			return pos
+		}
+	}
+	return -1
+}
+
+// renumberPins assigns pin positions 1..n in the given order.
+func (m *model) renumberPins(order []int) {
+	for pos, idx := range order {
+		m.projects[idx].Pinned = pos + 1
+	}
+}
+
+// togglePin pins the project at idx to the end of the pinned group, or unpins it.
+func (m *model) togglePin(idx int) error {
+	if err := m.guardShared(idx); err != nil {
+		return err
+	}
+	order := m.pinOrder()
+	if m.projects[idx].Pinned > 0 {
+		m.projects[idx].Pinned = 0
+		kept := order[:0]
+		for _, i := range order {
+			if i != idx {
+				kept = append(kept, i)
+			}
+		}
+		m.renumberPins(kept)
+	} else {
+		m.renumberPins(append(order, idx))
+	}
+	return m.saveProjects()
+}
+
+// movePin moves the pinned project at idx up (delta -1) or down (delta +1)
+// within the pinned group.
+func (m *model) movePin(idx, delta int) error {
+	if err := m.guardShared(idx); err != nil {
+		return err
+	}
+	if m.projects[idx].Pinned == 0 {
+		return fmt.Errorf("only pinned projects can be reordered (press %s to pin)", keyHint(keymap.List.Pin))
+	}
+	order := m.pinOrder()
+	pos := m.pinPosition(idx)
+	target := pos + delta
+	if target < 0 || target >= len(order) {
+		return nil
+	}
+	order[pos], order[target] = order[target], order[pos]
+	m.renumberPins(order)
+	return m.saveProjects()
+}
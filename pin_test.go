@@ -0,0 +1,105 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// pinnedNames lists the pinned projects in pin order.
+func pinnedNames(m *model) []string {
+	var names []string
+	for _, i := range m.pinOrder() {
+		names = append(names, m.projects[i].Name)
+	}
+	return names
+}
+
+func TestPins(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(t *testing.T, m *model) error
+		want    []string
+		wantErr bool
+	}{
+		{"pin goes last", func(t *testing.T, m *model) error {
+			return m.togglePin(indexOfName(t, m, "cli"))
+		}, []string{"api", "blog", "cli"}, false},
+		{"unpin closes the gap", func(t *testing.T, m *model) error {
+			return m.togglePin(indexOfName(t, m, "api"))
+		}, []string{"blog"}, false},
+		{"move down", func(t *testing.T, m *model) error {
+			return m.movePin(indexOfName(t, m, "api"), 1)
+		}, []string{"blog", "api"}, false},
+		{"move up", func(t *testing.T, m *model) error {
+			return m.movePin(indexOfName(t, m, "blog"), -1)
+		}, []string{"blog", "api"}, false},
+		{"already first", func(t *testing.T, m *model) error {
+			return m.movePin(indexOfName(t, m, "api"), -1)
+		}, []string{"api", "blog"}, false},
+		{"already last", func(t *testing.T, m *model) error {
+			return m.movePin(indexOfName(t, m, "blog"), 1)
+		}, []string{"api", "blog"}, false},
+		{"move unpinned", func(t *testing.T, m *model) error {
+			return m.movePin(indexOfName(t, m, "cli"), -1)
+		}, []string{"api", "blog"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, blog := testProject("api", t0), testProject("blog", t1)
+			api.Pinned, blog.Pinned = 1, 2
+			m := storeWith(t, api, blog, testProject("cli", t2))
+
+			if err := tt.change(t, &m); (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := pinnedNames(&m); !slices.Equal(got, tt.want) {
+				t.Errorf("pinned %v, want %v", got, tt.want)
+			}
+			for pos, i := range m.pinOrder() {
+				if m.projects[i].Pinned != pos+1 {
+					t.Errorf("%s pinned at %d, want %d", m.projects[i].Name, m.projects[i].Pinned, pos+1)
+				}
+			}
+		})
+	}
+}
+
+func TestPinsAfterMerge(t *testing.T) {
+	api, blog := testProject("api", t0), testProject("blog", t1)
+	api.Pinned, blog.Pinned = 1, 2
+	a, b := twoStores(t, api, blog, testProject("cli", t2))
+
+	// One process unpins api while another pins cli after blog.
+	if err := a.togglePin(indexOfName(t, &a, "api")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.togglePin(indexOfName(t, &b, "cli")); err != nil {
+		t.Fatal(err)
+	}
+	if got := pinnedNames(&b); !slices.Equal(got, []string{"blog", "cli"}) {
+		t.Fatalf("pinned %v after merging, want [blog cli]", got)
+	}
+	for pos, i := range b.pinOrder() {
+		if b.projects[i].Pinned != pos+1 {
+			t.Errorf("%s pinned at %d after merging, want %d", b.projects[i].Name, b.projects[i].Pinned, pos+1)
+		}
+	}
+	if err := b.movePin(indexOfName(t, &b, "cli"), -1); err != nil {
+		t.Fatal(err)
+	}
+	if got := pinnedNames(&b); !slices.Equal(got, []string{"cli", "blog"}) {
+		t.Errorf("pinned %v after moving cli up, want [cli blog]", got)
+	}
+}
+
+func TestPinPosition(t *testing.T) {
+	// Pin numbers with a gap, as a merge can leave them.
+	api, blog := testProject("api", t0), testProject("blog", t1)
+	api.Pinned, blog.Pinned = 2, 5
+	m := model{projects: []Project{testProject("cli", t2), blog, api}}
+	for name, want := range map[string]int{"api": 0, "blog": 1, "cli": -1} {
+		if got := m.pinPosition(indexOfName(t, &m, name)); got != want {
+			t.Errorf("pinPosition(%s) = %d, want %d", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// queryTerm is one space-separated part of a search query, e.g. `api`,
+// `tag:go`, `-path:~/tmp` or `"exact phrase"`.
+type queryTerm struct {
+	field  string // "" for free text, otherwise one of queryFields
+	value  string
+	negate bool
+	phrase bool // value was quoted; match it as a substring instead of fuzzily
+}
+
+// queryFields maps qualifier names to the field they search.
+var queryFields = map[string]string{
+	"name":        "name",
+	"tag":         "tag",
+	"path":        "path",
+	"desc":        "desc",
+	"description": "desc",
+	"is":          "is",
+	"book":        "book",
+	"id":          "id",
+}
+
+// archivedStates are the values of `is:` that select projects in the trash.
+var archivedStates = map[string]bool{
+	"archived": true,
+	"trashed":  true,
+	"deleted":  true,
+}
+
+// wantsArchived reports whether the query mentions archived projects at all,
+// either to include them (is:archived) or explicitly (-is:archived).
+// Otherwise archived projects are left out of results.
+func wantsArchived(terms []queryTerm) bool {
+	for _, t := range terms {
+		if t.field == "is" && archivedStates[strings.ToLower(t.value)] {
+			return true
+		}
+	}
+	return false
+}
+
+// parseQuery splits a query into terms. Terms are separated by spaces unless
+// quoted; a leading "-" negates a term and "field:" restricts it to a field.
+// Unknown qualifiers are treated as plain text.
+func parseQuery(q string) []queryTerm {
+	var terms []queryTerm
+	for _, tok := range tokenizeQuery(q) {
+		var t queryTerm
+		if strings.HasPrefix(tok, "-") && len(tok) > 1 {
+			t.negate = true
+			tok = tok[1:]
+		}
+		if i := strings.Index(tok, ":"); i > 0 {
+			if field, ok := queryFields[strings.ToLower(tok[:i])]; ok {
+				t.field = field
+				tok = tok[i+1:]
+			}
+		}
+		if unquoted, ok := unquote(tok); ok {
+			tok = unquoted
+			t.phrase = true
+		}
+		if tok == "" {
+			continue
+		}
+		t.value = tok
+		terms = append(terms, t)
+	}
+	return terms
+}
+
+// tokenizeQuery splits on whitespace outside of double quotes, keeping the
+// quotes so parseQuery can tell phrases apart.
+func tokenizeQuery(q string) []string {
+	var tokens []string
+	var cur strings.Builder
+	inQuote := false
+	for _, r := range q {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			cur.WriteRune(r)
+		case unicode.IsSpace(r) && !inQuote:
+			if cur.Len() > 0 {
+				tokens = append(tokens, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if cur.Len() > 0 {
+		tokens = append(tokens, cur.String())
+	}
+	return tokens
+}
+
+// unquote strips surrounding double quotes. An unterminated quote, as seen
+// while the user is still typing, counts as quoted.
+func unquote(s string) (string, bool) {
+	if !strings.HasPrefix(s, `"`) {
+		return s, false
+	}
+	s = strings.TrimPrefix(s, `"`)
+	return strings.TrimSuffix(s, `"`), true
+}
+
+// matchQuery reports whether p satisfies every term and returns its score.
+// Only free-text terms contribute to the score.
+func matchQuery(terms []queryTerm, p Project) (int, bool) {
+	total := 0
+	for _, t := range terms {
+		score := matchTerm(t, p)
+		if t.negate {
+			if score > 0 {
+				return 0, false
+			}
+			continue
+		}
+		if score == 0 {
+			return 0, false
+		}
+		if t.field == "" {
+			total += score
+		}
+	}
+	return total, true
+}
+
+// matchTerm scores a single term against p, ignoring negation. Zero means no match.
+func matchTerm(t queryTerm, p Project) int {
+	switch t.field {
+	case "name":
+		return containsFold(p.Name, t.value)
+	case "desc":
+		return containsFold(p.Description, t.value)
+	case "book":
+		return boolScore(strings.EqualFold(p.Book, t.value))
+	case "id":
+		return boolScore(strings.HasPrefix(strings.ToUpper(p.ID), strings.ToUpper(t.value)))
+	case "path":
+		value := t.value
+		if strings.HasPrefix(value, "~") {
+			value = expandPath(value)
+		}
+		return containsFold(p.Path, value)
+	case "is":
+		switch v := strings.ToLower(t.value); {
+		case archivedStates[v]:
+			return boolScore(p.archived())
+		case v == "pinned":
+			return boolScore(p.Pinned > 0)
+		case v == "missing":
+			return boolScore(pathMissing(p))
+		case v == "shared":
+			return boolScore(p.Shared != "")
+		}
+		return 0
+	case "tag":
+		for _, tag := range p.Tags {
+			if t.phrase && strings.EqualFold(tag, t.value) {
+				return 1
+			}
+			if !t.phrase && strings.HasPrefix(strings.ToLower(tag), strings.ToLower(t.value)) {
+				return 1
+			}
+		}
+		return 0
+	}
+
+	if t.phrase {
+		for _, field := range []string{p.Name, p.Description, p.Path, strings.Join(p.Tags, " ")} {
+			if containsFold(field, t.value) > 0 {
+				return 1000
+			}
+		}
+		return 0
+	}
+	return projectScore(t.value, p)
+}
+
+// projectScore is the best fuzzy score of q across a project's searchable fields.
+func projectScore(q string, p Project) int {
+	score := fuzzyScore(q, p.Name)
+	for _, tag := range p.Tags {
+		score = max(score, fuzzyScore(q, tag))
+	}
+	score = max(score, fuzzyScore(q, p.Description))
+	score = max(score, fuzzyScore(q, p.Path)/2) // Lower weight for path
+	return score
+}
+
+func boolScore(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func containsFold(s, substr string) int {
+	if strings.Contains(strings.ToLower(s), strings.ToLower(substr)) {
+		return 1
+	}
+	return 0
+}
+
+// highlightTerm picks the text to highlight in the list for a query: the
+// first positive free-text or name term.
+func highlightTerm(terms []queryTerm) string {
+	for _, t := range terms {
+		if !t.negate && (t.field == "" || t.field == "name") {
+			return t.value
+		}
+	}
+	return ""
+}
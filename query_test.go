@@ -0,0 +1,112 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []queryTerm
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"api", []queryTerm{{value: "api"}}},
+		{"api  web", []queryTerm{{value: "api"}, {value: "web"}}},
+		{"tag:go", []queryTerm{{field: "tag", value: "go"}}},
+		{"TAG:go", []queryTerm{{field: "tag", value: "go"}}},
+		{"description:cli", []queryTerm{{field: "desc", value: "cli"}}},
+		{"-path:~/tmp", []queryTerm{{field: "path", value: "~/tmp", negate: true}}},
+		{`"exact phrase"`, []queryTerm{{value: "exact phrase", phrase: true}}},
+		{`-name:"my api"`, []queryTerm{{field: "name", value: "my api", negate: true, phrase: true}}},
+		{`"still typ`, []queryTerm{{value: "still typ", phrase: true}}},
+		{"foo:bar", []queryTerm{{value: "foo:bar"}}},
+		{"-", []queryTerm{{value: "-"}}},
+		{"tag: api", []queryTerm{{value: "api"}}},
+		{`""`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := parseQuery(tt.query); !slices.Equal(got, tt.want) {
+				t.Errorf("parseQuery(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchQuery(t *testing.T) {
+	p := Project{
+		ID:          "01HK153X00AN87Z7RPYW0AG9J5",
+		Name:        "phonebook",
+		Path:        "/home/me/src/phonebook",
+		Tags:        []string{"golang", "tui"},
+		Description: "Terminal project launcher",
+		Pinned:      1,
+	}
+	trashed := p
+	trashed.DeletedAt = &t0
+	missing := p
+	missing.Path = t.TempDir() + "/gone"
+	present := p
+	present.Path = t.TempDir()
+	inBook := p
+	inBook.Book = "work"
+	shared := p
+	shared.Shared = "/team/projects.json"
+
+	tests := []struct {
+		name    string
+		query   string
+		project Project
+		want    bool
+	}{
+		{"empty query", "", p, true},
+		{"fuzzy name", "phbk", p, true},
+		{"fuzzy miss", "zzz", p, false},
+		{"every term must match", "phone zzz", p, false},
+		{"name", "name:BOOK", p, true},
+		{"tag prefix", "tag:go", p, true},
+		{"quoted tag is exact", `tag:"go"`, p, false},
+		{"quoted tag", `tag:"golang"`, p, true},
+		{"description", "desc:launcher", p, true},
+		{"phrase", `"project launcher"`, p, true},
+		{"phrase in another order", `"launcher project"`, p, false},
+		{"negated", "-tag:tui", p, false},
+		{"negated miss", "-tag:web", p, true},
+		{"path", "path:src/PHONE", p, true},
+		{"path elsewhere", "-path:/tmp", p, true},
+		{"unknown qualifier is text", "foo:bar", p, false},
+		{"is:pinned", "is:pinned", p, true},
+		{"is:archived", "is:archived", trashed, true},
+		{"not archived", "is:archived", p, false},
+		{"-is:trashed", "-is:trashed", trashed, false},
+		{"is:missing", "is:missing", missing, true},
+		{"not missing", "is:missing", present, false},
+		{"unknown state", "is:bogus", p, false},
+		{"id", "id:01HK153X00AN87Z7RPYW0AG9J5", p, true},
+		{"id prefix", "id:01hk153x", p, true},
+		{"other id", "id:01HK153X01", p, false},
+		{"book", "book:WORK", inBook, true},
+		{"book is exact", "book:wor", inBook, false},
+		{"other book", "-book:work", inBook, false},
+		{"is:shared", "is:shared", shared, true},
+		{"not shared", "is:shared", p, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := matchQuery(parseQuery(tt.query), tt.project); got != tt.want {
+				t.Errorf("matchQuery(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchQueryScoresFreeTextOnly(t *testing.T) {
+	p := Project{Name: "api", Tags: []string{"go"}}
+	plain, _ := matchQuery(parseQuery("api"), p)
+	qualified, _ := matchQuery(parseQuery("api tag:go"), p)
+	if plain == 0 || plain != qualified {
+		t.Errorf("score of \"api\" = %d, of \"api tag:go\" = %d; want the same, above zero", plain, qualified)
+	}
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// defaultScanDepth is how many directory levels below each root are searched
+// when the config doesn't say otherwise.
+const defaultScanDepth = 3
+
+// projectMarkers are files whose presence makes a directory a project.
+var projectMarkers = []string{
+	".git",
+	"go.mod",
+	"Cargo.toml",
+	"package.json",
+	"deno.json",
+	"pyproject.toml",
+	"setup.py",
+	"requirements.txt",
+	"Gemfile",
+	"pom.xml",
+	"build.gradle",
+	"build.gradle.kts",
+	"composer.json",
+	"mix.exs",
+	"pubspec.yaml",
+	"CMakeLists.txt",
+	"stack.yaml",
+	"dune-project",
+}
+
+// skipDirs are never descended into while scanning.
+var skipDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"target":       true,
+	"dist":         true,
+	"build":        true,
+	"__pycache__":  true,
+}
+
+// scanCandidate is a discovered directory with suggested project metadata.
+type scanCandidate struct {
+	project  Project
+	markers  []string
+	selected bool
+}
+
+// scanDoneMsg carries the result of a background scan.
+type scanDoneMsg struct {
+	candidates []scanCandidate
+	err        error
+}
+
+// scanRoots returns the configured roots, or the home directory.
+func (c Config) scanRoots() []string {
+	if len(c.ScanRoots) > 0 {
+		return c.ScanRoots
+	}
+	home, _ := os.UserHomeDir()
+	return []string{home}
+}
+
+func (c Config) scanDepth() int {
+	if c.ScanDepth > 0 {
+		return c.ScanDepth
+	}
+	return defaultScanDepth
+}
+
+// scanForProjects walks roots up to depth levels deep looking for project
+// markers. Directories in known (by normalized path) are skipped, and so is
+// everything inside a detected project.
+func scanForProjects(roots []string, depth int, known map[string]bool) ([]scanCandidate, error) {
+	var candidates []scanCandidate
+	seen := make(map[string]bool)
+
+	for _, root := range roots {
+		root = expandPath(root)
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("%s is not a directory", root)
+		}
+
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				// Unreadable directories are skipped rather than aborting the scan.
+				if d != nil && d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.IsDir() {
+				return nil
+			}
+			if path != root && (strings.HasPrefix(d.Name(), ".") || skipDirs[d.Name()]) {
+				return filepath.SkipDir
+			}
+			// Known paths are normalized, so a root reached through a
+			// symlink still finds the projects under it.
+			key := normalizePath(path)
+			if known[key] || seen[key] {
+				return filepath.SkipDir
+			}
+
+			if markers := findMarkers(path); len(markers) > 0 {
+				seen[key] = true
+				candidates = append(candidates, newScanCandidate(path, markers))
+				return filepath.SkipDir
+			}
+
+			rel, _ := filepath.Rel(root, path)
+			if rel != "." && strings.Count(rel, string(filepath.Separator))+1 >= depth {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].project.Path < candidates[j].project.Path
+	})
+	return candidates, nil
+}
+
+// findMarkers lists the project markers present in dir.
+func findMarkers(dir string) []string {
+	var found []string
+	for _, mk := range projectMarkers {
+		if _, err := os.Stat(filepath.Join(dir, mk)); err == nil {
+			found = append(found, mk)
+		}
+	}
+	return found
+}
+
+func newScanCandidate(path string, markers []string) scanCandidate {
+	path = normalizePath(path)
+	return scanCandidate{
+		project: Project{
+			Name:        filepath.Base(path),
+			Path:        path,
+			Tags:        detectStack(path),
+			Description: suggestDescription(path),
+		},
+		markers:  markers,
+		selected: true,
+	}
+}
+
+// suggestDescription looks for a description in common manifests, falling
+// back to the first paragraph line of the README.
+func suggestDescription(dir string) string {
+	if data, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil {
+		var pkg struct {
+			Description string `json:"description"`
+		}
+		if json.Unmarshal(data, &pkg) == nil && pkg.Description != "" {
+			return pkg.Description
+		}
+	}
+	for _, manifest := range []string{"Cargo.toml", "pyproject.toml"} {
+		if d := tomlStringValue(filepath.Join(dir, manifest), "description"); d != "" {
+			return d
+		}
+	}
+	for _, readme := range []string{"README.md", "README", "README.rst", "readme.md"} {
+		if d := readmeSummary(filepath.Join(dir, readme)); d != "" {
+			return d
+		}
+	}
+	return ""
+}
+
+// tomlStringValue returns the first `key = "value"` in a TOML file. It is
+// only meant for simple manifest fields.
+func tomlStringValue(path, key string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		k, v, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(k) != key {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			return v[1 : len(v)-1]
+		}
+	}
+	return ""
+}
+
+// readmeSummary returns the first line of prose in a README.
+func readmeSummary(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") ||
+			strings.HasPrefix(line, "[") || strings.HasPrefix(line, "<") ||
+			strings.HasPrefix(line, "=") || strings.HasPrefix(line, "-") {
+			continue
+		}
+		return truncate(line, 120)
+	}
+	return ""
+}
+
+// knownPaths returns the normalized paths of all registered projects.
+func (m *model) knownPaths() map[string]bool {
+	known := make(map[string]bool, len(m.projects))
+	for _, p := range m.projects {
+		known[normalizePath(p.Path)] = true
+	}
+	return known
+}
+
+// scanCmd scans the configured roots in the background.
+func (m model) scanCmd() tea.Cmd {
+	roots, depth, known := m.config.scanRoots(), m.config.scanDepth(), m.knownPaths()
+	return func() tea.Msg {
+		candidates, err := scanForProjects(roots, depth, known)
+		return scanDoneMsg{candidates: candidates, err: err}
+	}
+}
+
+// startScan switches to the scan screen and kicks off a scan.
+func (m *model) startScan() tea.Cmd {
+	m.mode = viewScan
+	m.scanTitle = "🔍 Discover Projects"
+	m.scanning = true
+	m.scanResults = nil
+	m.scanCursor = 0
+	m.statusMessage = ""
+	return m.scanCmd()
+}
+
+// updateScan handles keys on the scan screen.
+func (m model) updateScan(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	sk := keymap.Scan
+	switch {
+	case msg.String() == quitKey:
+		return m, tea.Quit
+	case key.Matches(msg, sk.Cancel):
+		m.mode = viewList
+		m.scanResults = nil
+		m.scanning = false
+		m.statusMessage = "Cancelled"
+		m.isError = false
+		if m.scanQuit {
+			return m, tea.Quit
+		}
+		return m, nil
+	}
+	if m.scanning || len(m.scanResults) == 0 {
+		return m, nil
+	}
+
+	switch {
+	case key.Matches(msg, sk.Down):
+		m.scanCursor = (m.scanCursor + 1) % len(m.scanResults)
+	case key.Matches(msg, sk.Up):
+		m.scanCursor = (m.scanCursor - 1 + len(m.scanResults)) % len(m.scanResults)
+	case key.Matches(msg, sk.Toggle):
+		m.scanResults[m.scanCursor].selected = !m.scanResults[m.scanCursor].selected
+	case key.Matches(msg, sk.ToggleAll):
+		all := true
+		for _, c := range m.scanResults {
+			all = all && c.selected
+		}
+		for i := range m.scanResults {
+			m.scanResults[i].selected = !all
+		}
+	case key.Matches(msg, sk.Import):
+		var picked []Project
+		for _, c := range m.scanResults {
+			if c.selected {
+				picked = append(picked, c.project)
+			}
+		}
+		if len(picked) == 0 {
+			m.statusMessage = "Nothing selected"
+			m.isError = true
+			return m, nil
+		}
+		m.checkpoint(fmt.Sprintf("import of %d projects", len(picked)))
+		if err := m.addProjects(picked); err != nil {
+			m.dropCheckpoint()
+			m.statusMessage = fmt.Sprintf("Error: %v", err)
+			m.isError = true
+			return m, nil
+		}
+		m.mode = viewList
+		m.scanResults = nil
+		m.statusMessage = fmt.Sprintf("✓ Imported %d projects", len(picked))
+		m.isError = false
+		m.applyFilter(m.textInput.Value())
+		if m.scanQuit {
+			return m, tea.Quit
+		}
+	}
+	return m, nil
+}
+
+// scanView renders the scan checklist.
+func (m model) scanView() string {
+	var b strings.Builder
+
+	header := lipgloss.NewStyle().
+		Foreground(primaryColor).
+		Bold(true).
+		Background(bgColor).
+		Padding(1, 2).
+		MarginBottom(1).
+		Width(70).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(primaryColor).
+		Render(m.scanTitle)
+	b.WriteString(header + "\n")
+
+	muted := lipgloss.NewStyle().Foreground(mutedColor).Italic(true)
+	switch {
+	case m.scanning:
+		b.WriteString(muted.Render(fmt.Sprintf("Scanning %s (depth %d)…",
+			strings.Join(m.config.scanRoots(), ", "), m.config.scanDepth())) + "\n")
+	case len(m.scanResults) == 0:
+		b.WriteString(muted.Render("No new projects found") + "\n")
+	default:
+		selected := 0
+		for _, c := range m.scanResults {
+			if c.selected {
+				selected++
+			}
+		}
+		b.WriteString(muted.Render(fmt.Sprintf("%d found, %d selected", len(m.scanResults), selected)) + "\n\n")
+
+		// Three lines per entry; keep the cursor in view.
+		maxDisplay := max((m.viewport.Height-6)/3, 1)
+		start := max(min(m.scanCursor-maxDisplay/2, len(m.scanResults)-maxDisplay), 0)
+		end := min(start+maxDisplay, len(m.scanResults))
+
+		for i := start; i < end; i++ {
+			c := m.scanResults[i]
+			box := "[ ]"
+			if c.selected {
+				box = "[x]"
+			}
+			line := box + " " + c.project.Name
+			if len(c.project.Tags) > 0 {
+				line += " " + renderTags(c.project.Tags, "")
+			}
+			if i == m.scanCursor {
+				b.WriteString(selectedItemStyle.Render("▶ "+line) + "\n")
+			} else {
+				b.WriteString(normalItemStyle.Render(line) + "\n")
+			}
+			b.WriteString(pathStyle.Render("       "+c.project.Path) + "\n")
+			desc := c.project.Description
+			if desc == "" {
+				desc = strings.Join(c.markers, ", ")
+			}
+			b.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render("       "+truncate(desc, 70)) + "\n")
+		}
+	}
+
+	b.WriteString(m.helpBar(keymap.Scan.shortHelp(), ""))
+
+	if m.statusMessage != "" {
+		if m.isError {
+			b.WriteString("\n" + errorStyle.Render("✗ "+m.statusMessage))
+		} else {
+			b.WriteString("\n" + statusStyle.Render(m.statusMessage))
+		}
+	}
+
+	return lipgloss.NewStyle().Padding(1, 2).Render(b.String())
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeTree creates files under root, given as relative path → contents.
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestScanForProjects(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"api/go.mod":                 "module api",
+		"api/web/package.json":       "{}",
+		"site/package.json":          `{"description": "The site"}`,
+		"work/tool/Cargo.toml":       "[package]",
+		"work/deep/er/lib/Gemfile":   "",
+		"node_modules/left/setup.py": "",
+		".cache/thing/go.mod":        "",
+		"known/pyproject.toml":       "",
+		"notes/README.md":            "not a project",
+	})
+
+	tests := []struct {
+		name  string
+		depth int
+		known map[string]bool
+		want  []string
+	}{
+		{"top level only", 1, nil, []string{"api", "known", "site"}},
+		{"two levels", 2, nil, []string{"api", "known", "site", "work/tool"}},
+		{"known paths are skipped", 2, map[string]bool{filepath.Join(root, "known"): true}, []string{"api", "site", "work/tool"}},
+		{"deep", 4, nil, []string{"api", "known", "site", "work/deep/er/lib", "work/tool"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates, err := scanForProjects([]string{root}, tt.depth, tt.known)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, c := range candidates {
+				rel, _ := filepath.Rel(root, c.project.Path)
+				got = append(got, rel)
+				if !c.selected || c.project.Name != filepath.Base(c.project.Path) {
+					t.Errorf("candidate %+v: want it selected and named after its directory", c)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("found %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanForProjectsThroughSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "code")
+	writeTree(t, target, map[string]string{"work/api/go.mod": "", "work/known/go.mod": ""})
+	link := filepath.Join(dir, "src")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skip(err)
+	}
+	// knownPaths holds normalized paths, which resolve the link.
+	known := map[string]bool{normalizePath(filepath.Join(link, "work", "known")): true}
+
+	// The same directory scanned through the link and directly.
+	roots := []string{filepath.Join(link, "work"), filepath.Join(target, "work")}
+	candidates, err := scanForProjects(roots, 2, known)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range candidates {
+		got = append(got, c.project.Path)
+	}
+	if want := []string{normalizePath(filepath.Join(target, "work", "api"))}; !slices.Equal(got, want) {
+		t.Errorf("found %v, want %v", got, want)
+	}
+}
+
+func TestScanForProjectsBadRoot(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	writeTree(t, filepath.Dir(file), map[string]string{"file": ""})
+	for _, root := range []string{file, filepath.Join(file, "missing")} {
+		if _, err := scanForProjects([]string{root}, 2, nil); err == nil {
+			t.Errorf("scanForProjects(%s) succeeded", root)
+		}
+	}
+}
+
+func TestSuggestDescription(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"package.json", map[string]string{"package.json": `{"description": "A web app"}`, "README.md": "Other"}, "A web app"},
+		{"Cargo.toml", map[string]string{"Cargo.toml": "[package]\nname = \"x\"\ndescription = \"A crate\"\n"}, "A crate"},
+		{"pyproject.toml single quotes", map[string]string{"pyproject.toml": "description = 'A tool'"}, "A tool"},
+		{"README skips headings and badges", map[string]string{
+			"README.md": "# Title\n\n[![build](x)](y)\n<img src=x>\n\nDoes things.\nMore.\n",
+		}, "Does things."},
+		{"empty manifest falls back to README", map[string]string{"package.json": `{"name": "x"}`, "README": "Plain readme"}, "Plain readme"},
+		{"nothing", map[string]string{"go.mod": "module x"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTree(t, dir, tt.files)
+			if got := suggestDescription(dir); got != tt.want {
+				t.Errorf("suggestDescription() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
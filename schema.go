@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// storeVersion is the version of the projects file this build writes.
+//
+//	1: a bare JSON array of projects
+//	2: {"version": 2, "projects": [...]}, every project has an ID
+const storeVersion = 2
+
+// storeFile is the envelope projects files are written in since version 2.
+type storeFile struct {
+	Version  int       `json:"version"`
+	Projects []Project `json:"projects"`
+}
+
+// migrations[i] upgrades projects read from a version i+1 file to version
+// i+2. Add a step here, and bump storeVersion, whenever the format changes.
+var migrations = []func([]Project) []Project{
+	assignIDs, // 1 → 2
+}
+
+// errNewerStore is returned for files written by a newer phonebook, which
+// this build must not overwrite.
+var errNewerStore = errors.New("projects file was written by a newer version of phonebook; please upgrade")
+
+// decodeStore reads a projects file of any version and returns its projects
+// as written, along with that version.
+func decodeStore(data []byte) ([]Project, int, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var projects []Project
+		if err := json.Unmarshal(data, &projects); err != nil {
+			return nil, 0, err
+		}
+		return projects, 1, nil
+	}
+	var f storeFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, 0, err
+	}
+	if f.Version < 2 {
+		return nil, 0, fmt.Errorf("missing or invalid version %d", f.Version)
+	}
+	return f.Projects, f.Version, nil
+}
+
+// migrate upgrades projects read from a file of the given version to
+// storeVersion.
+func migrate(projects []Project, version int) ([]Project, error) {
+	if version > storeVersion {
+		return nil, fmt.Errorf("%w (file version %d, this build understands %d)", errNewerStore, version, storeVersion)
+	}
+	for v := version; v < storeVersion; v++ {
+		projects = migrations[v-1](projects)
+	}
+	return projects, nil
+}
+
+// encodeStore writes projects in the current format.
+func encodeStore(projects []Project) ([]byte, error) {
+	if projects == nil {
+		projects = []Project{}
+	}
+	return json.MarshalIndent(storeFile{Version: storeVersion, Projects: projects}, "", "  ")
+}
+
+// assignIDs gives every project without an ID, or with one already taken,
+// a new one. Projects that have never had an ID get stableID so that copies
+// of the same phonebook agree on it.
+func assignIDs(projects []Project) []Project {
+	seen := make(map[string]bool, len(projects))
+	for i := range projects {
+		p := &projects[i]
+		switch {
+		case p.ID == "":
+			p.ID = stableID(p.CreatedAt)
+			if seen[p.ID] {
+				p.ID = newID(p.CreatedAt)
+			}
+		case seen[p.ID]:
+			p.ID = newID(p.CreatedAt)
+		}
+		seen[p.ID] = true
+	}
+	return projects
+}
+
+// migrateStore rewrites a projects file loaded from an older version in the
+// current format, keeping the original next to it first.
+func (m *model) migrateStore(data []byte, version int) error {
+	backup := fmt.Sprintf("%s.v%d-%s.bak", m.projectsFile, version, time.Now().Format("20060102-150405"))
+	if err := writeFileAtomic(backup, data, 0o644); err != nil {
+		return fmt.Errorf("backing up projects before upgrading: %w", err)
+	}
+	if err := m.saveProjects(); err != nil {
+		return fmt.Errorf("upgrading projects file: %w", err)
+	}
+	return nil
+}
+
+// hasID reports whether any project already uses id.
+func (m *model) hasID(id string) bool {
+	for _, p := range m.projects {
+		if p.ID == id {
+			return true
+		}
+	}
+	return false
+}
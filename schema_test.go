@@ -0,0 +1,209 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeStore(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		wantNames   []string
+		wantVersion int
+		wantErr     bool
+	}{
+		{"version 1 array", `[{"name":"api"},{"name":"blog"}]`, []string{"api", "blog"}, 1, false},
+		{"version 1 with leading space", " \n[{\"name\":\"api\"}]", []string{"api"}, 1, false},
+		{"empty version 1", `[]`, nil, 1, false},
+		{"version 2", `{"version":2,"projects":[{"id":"A","name":"api"}]}`, []string{"api"}, 2, false},
+		{"newer version", `{"version":3,"projects":[]}`, nil, 3, false},
+		{"missing version", `{"projects":[]}`, nil, 0, true},
+		{"version 1 envelope", `{"version":1,"projects":[]}`, nil, 0, true},
+		{"malformed", `{"version":2,`, nil, 0, true},
+		{"empty file", ``, nil, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projects, version, err := decodeStore([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeStore() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var names []string
+			for _, p := range projects {
+				names = append(names, p.Name)
+			}
+			if !slices.Equal(names, tt.wantNames) || version != tt.wantVersion {
+				t.Errorf("decodeStore() = %v, version %d; want %v, version %d", names, version, tt.wantNames, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestDecodeStoreLegacyTag(t *testing.T) {
+	projects, _, err := decodeStore([]byte(`[{"name":"api","tag":"go, web"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := projects[0].Tags; !slices.Equal(got, []string{"go", "web"}) {
+		t.Errorf("tags = %v, want [go web]", got)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	unmigrated := func(name string, created time.Time) Project {
+		p := testProject(name, created)
+		p.ID = ""
+		return p
+	}
+	api, blog := unmigrated("api", t0), unmigrated("blog", t1)
+	keep := testProject("keep", t2)
+	keep.ID = "01HK153X00AN87Z7RPYW0AG9J5"
+
+	tests := []struct {
+		name     string
+		projects []Project
+		version  int
+		check    func(t *testing.T, got []Project)
+		wantErr  error
+	}{
+		{
+			name:     "version 1 gets stable IDs",
+			projects: []Project{api, blog},
+			version:  1,
+			check: func(t *testing.T, got []Project) {
+				if got[0].ID != stableID(t0) || got[1].ID != stableID(t1) {
+					t.Errorf("IDs = %s, %s; want %s, %s", got[0].ID, got[1].ID, stableID(t0), stableID(t1))
+				}
+			},
+		},
+		{
+			name:     "same creation time gets another ID",
+			projects: []Project{api, unmigrated("api-copy", t0)},
+			version:  1,
+			check: func(t *testing.T, got []Project) {
+				if got[0].ID != stableID(t0) || got[1].ID == got[0].ID || got[1].ID == "" {
+					t.Errorf("IDs = %s, %s; want %s and a different one", got[0].ID, got[1].ID, stableID(t0))
+				}
+			},
+		},
+		{
+			name:     "duplicate names and creation times get distinct IDs",
+			projects: []Project{api, api, api},
+			version:  1,
+			check: func(t *testing.T, got []Project) {
+				if got[0].ID == got[1].ID || got[1].ID == got[2].ID || got[0].ID == got[2].ID {
+					t.Errorf("IDs = %s, %s, %s; want three different ones", got[0].ID, got[1].ID, got[2].ID)
+				}
+			},
+		},
+		{
+			name:     "a duplicate ID is replaced",
+			projects: []Project{keep, keep},
+			version:  1,
+			check: func(t *testing.T, got []Project) {
+				if got[0].ID != keep.ID || got[1].ID == keep.ID || got[1].ID == "" {
+					t.Errorf("IDs = %s, %s; want %s and a different one", got[0].ID, got[1].ID, keep.ID)
+				}
+			},
+		},
+		{
+			name:     "existing IDs are kept",
+			projects: []Project{keep},
+			version:  1,
+			check: func(t *testing.T, got []Project) {
+				if got[0].ID != keep.ID {
+					t.Errorf("ID = %s, want %s", got[0].ID, keep.ID)
+				}
+			},
+		},
+		{
+			name:     "current version is left alone",
+			projects: []Project{api},
+			version:  storeVersion,
+			check: func(t *testing.T, got []Project) {
+				if got[0].ID != "" {
+					t.Errorf("ID = %s, want none", got[0].ID)
+				}
+			},
+		},
+		{
+			name:     "newer version",
+			projects: []Project{keep},
+			version:  storeVersion + 1,
+			wantErr:  errNewerStore,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := migrate(cloneProjects(tt.projects), tt.version)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("migrate() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.check != nil {
+				tt.check(t, got)
+			}
+		})
+	}
+}
+
+func TestLoadVersion1Store(t *testing.T) {
+	// Two projects share a name and were added in the same instant.
+	v1 := `[
+  {"name": "api", "path": "/src/api", "created_at": "2024-01-01T12:00:00Z"},
+  {"name": "api", "path": "/work/api", "created_at": "2024-01-01T12:00:00Z"},
+  {"name": "blog", "path": "/src/blog", "created_at": "2024-01-01T13:00:00Z"}
+]`
+	file := filepath.Join(t.TempDir(), "projects.json")
+	if err := os.WriteFile(file, []byte(v1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m := model{projectsFile: file}
+	if err := m.loadProjects(); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.projects) != 3 {
+		t.Fatalf("loaded %d projects, want 3", len(m.projects))
+	}
+	if m.projects[0].ID == m.projects[1].ID {
+		t.Errorf("both projects called api got ID %s", m.projects[0].ID)
+	}
+
+	// The file is rewritten as version 2 with the original kept aside.
+	data, _ := os.ReadFile(file)
+	if _, version, err := decodeStore(data); err != nil || version != storeVersion {
+		t.Errorf("store rewritten as version %d (%v), want %d", version, err, storeVersion)
+	}
+	if backups, _ := filepath.Glob(file + ".v1-*.bak"); len(backups) != 1 {
+		t.Errorf("version 1 backups %v, want one", backups)
+	}
+
+	// Reloading keeps the IDs the migration gave, so both projects survive
+	// an edit to one of them.
+	ids := []string{m.projects[0].ID, m.projects[1].ID}
+	m.projects[1].Description = "work copy"
+	if err := m.saveProjects(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.loadProjects(); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range m.projects {
+		if p.Name == "api" {
+			got = append(got, p.ID+" "+p.Description)
+		}
+	}
+	want := []string{ids[0] + " ", ids[1] + " work copy"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("after reloading: %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const posixInit = `# phonebook shell integration
+# Add to your shell rc file: eval "$(phonebook init %[2]s)"
+%[1]s() {
+  local dir
+  dir="$(command phonebook --print-path "$@")" || return
+  [ -n "$dir" ] && cd -- "$dir"
+}
+`
+
+const fishInit = `# phonebook shell integration
+# Add to ~/.config/fish/config.fish: phonebook init fish | source
+function %[1]s
+    set -l dir (command phonebook --print-path $argv); or return
+    test -n "$dir"; and cd -- $dir
+end
+`
+
+// cmdInit prints a shell function that jumps to the project picked in the TUI.
+func cmdInit(args []string) error {
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	name := fs.String("cmd", "pb", "name of the generated shell function")
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return usageError("init takes one of bash, zsh, fish")
+	}
+	if *name == "" || strings.ContainsAny(*name, " \t\n;&|$`'\"()<>") {
+		return usageError("invalid function name %q", *name)
+	}
+
+	switch pos[0] {
+	case "bash", "zsh":
+		fmt.Fprintf(os.Stdout, posixInit, *name, pos[0])
+	case "fish":
+		fmt.Fprintf(os.Stdout, fishInit, *name)
+	default:
+		return usageError("unsupported shell %q (want bash, zsh or fish)", pos[0])
+	}
+	return nil
+}
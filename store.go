@@ -0,0 +1,398 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxBackups is how many previous versions of the store are kept next to it
+// as projects.json.bak.1 (newest) through projects.json.bak.N (oldest).
+const maxBackups = 5
+
+// corruptStoreError is returned by loadProjects when the store exists but
+// cannot be parsed. backup is the newest backup that parses, if any.
+type corruptStoreError struct {
+	err    error
+	backup string
+}
+
+func (e *corruptStoreError) Error() string {
+	if e.backup != "" {
+		return fmt.Sprintf("projects file is corrupt (%v); a backup is available at %s", e.err, e.backup)
+	}
+	return fmt.Sprintf("projects file is corrupt (%v) and no valid backup was found", e.err)
+}
+
+func (e *corruptStoreError) Unwrap() error { return e.err }
+
+// storePollInterval is how often the TUI checks projects.json for changes
+// made by other processes.
+const storePollInterval = 2 * time.Second
+
+// fileState identifies a version of the store on disk.
+type fileState struct {
+	modTime time.Time
+	size    int64
+	sum     [sha256.Size]byte
+}
+
+// newFileState describes path after data was read from or written to it.
+func newFileState(path string, data []byte) fileState {
+	st := fileState{sum: sha256.Sum256(data)}
+	if info, err := os.Stat(path); err == nil {
+		st.modTime = info.ModTime()
+		st.size = info.Size()
+	}
+	return st
+}
+
+// storeTickMsg asks the model to check the store for outside changes.
+type storeTickMsg struct{}
+
+// storeChangedMsg reports that another process changed the store.
+type storeChangedMsg struct{}
+
+func watchStore() tea.Cmd {
+	return tea.Tick(storePollInterval, func(time.Time) tea.Msg {
+		return storeTickMsg{}
+	})
+}
+
+// changedOnDisk reports whether the store differs from the version last
+// loaded or saved. The cheap stat comparison runs first; contents are only
+// hashed when it suggests a change.
+func (m *model) changedOnDisk() bool {
+	if m.allBooks {
+		// The merged view is read-only and reloaded with r.
+		return false
+	}
+	info, err := os.Stat(m.projectsFile)
+	if err != nil {
+		return false
+	}
+	if info.ModTime().Equal(m.disk.modTime) && info.Size() == m.disk.size {
+		return false
+	}
+	data, err := os.ReadFile(m.projectsFile)
+	if err != nil {
+		return false
+	}
+	if sha256.Sum256(data) == m.disk.sum {
+		// Touched but not modified; remember the new stat so we stop re-hashing.
+		m.disk = newFileState(m.projectsFile, data)
+		return false
+	}
+	return true
+}
+
+// projectKey identifies a project across versions of the store and across
+// machines: its ID never changes once assigned. Shared projects are
+// identified by their layer and name.
+func projectKey(p Project) string {
+	if p.Shared != "" {
+		// Hand-written layers may leave the ID out.
+		return "shared:" + p.Shared + ":" + p.Name
+	}
+	return p.ID
+}
+
+func sameProject(a, b Project) bool {
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return bytes.Equal(ja, jb)
+}
+
+func cloneProjects(ps []Project) []Project {
+	return append([]Project(nil), ps...)
+}
+
+// mergeProjects does a three-way merge of our in-memory projects with the
+// version another process or machine wrote (theirs), relative to the version
+// both started from (base). Additions and deletions on either side are kept;
+// when both sides changed a project, mergeProject combines the changes.
+func mergeProjects(base, ours, theirs []Project) []Project {
+	index := func(ps []Project) map[string]Project {
+		byKey := make(map[string]Project, len(ps))
+		for _, p := range ps {
+			byKey[projectKey(p)] = p
+		}
+		return byKey
+	}
+	baseBy, oursBy, theirsBy := index(base), index(ours), index(theirs)
+
+	out := []Project{}
+	// Our additions go first, matching addProject.
+	for _, o := range ours {
+		k := projectKey(o)
+		_, inBase := baseBy[k]
+		_, inTheirs := theirsBy[k]
+		if !inBase && !inTheirs {
+			out = append(out, o)
+		}
+	}
+
+	for _, t := range theirs {
+		k := projectKey(t)
+		b, inBase := baseBy[k]
+		o, inOurs := oursBy[k]
+		switch {
+		case !inBase && !inOurs:
+			out = append(out, t) // added elsewhere
+		case !inBase:
+			out = append(out, o) // added on both sides; keep ours
+		case !inOurs:
+			if !sameProject(b, t) {
+				out = append(out, t) // we deleted it, but it was edited elsewhere
+			}
+		case sameProject(o, b):
+			out = append(out, t)
+		case sameProject(t, b):
+			out = append(out, o)
+		default:
+			out = append(out, mergeProject(b, o, t))
+		}
+	}
+
+	// Deleted elsewhere: drop unless we changed it in the meantime.
+	for _, o := range ours {
+		k := projectKey(o)
+		b, inBase := baseBy[k]
+		_, inTheirs := theirsBy[k]
+		if inBase && !inTheirs && !sameProject(o, b) {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+// mergeProject combines the changes both sides made to one project field by
+// field. Tags are merged as a set, opens are combined and their counts added
+// up; any other field changed on both sides takes the more recent edit, with
+// ties going to ours.
+func mergeProject(base, ours, theirs Project) Project {
+	theirsNewer := theirs.UpdatedAt.After(ours.UpdatedAt)
+	pick := func(b, o, t string) string {
+		switch {
+		case o == b:
+			return t
+		case t == b || !theirsNewer:
+			return o
+		}
+		return t
+	}
+
+	out := ours
+	out.Name = pick(base.Name, ours.Name, theirs.Name)
+	out.Path = pick(base.Path, ours.Path, theirs.Path)
+	out.Description = pick(base.Description, ours.Description, theirs.Description)
+	out.Opener = pick(base.Opener, ours.Opener, theirs.Opener)
+	out.Remote = pick(base.Remote, ours.Remote, theirs.Remote)
+	out.Tags = mergeTags(base.Tags, ours.Tags, theirs.Tags)
+	if theirsNewer {
+		out.UpdatedAt = theirs.UpdatedAt
+	}
+
+	switch {
+	case ours.Pinned == base.Pinned:
+		out.Pinned = theirs.Pinned
+	case theirs.Pinned != base.Pinned && theirsNewer:
+		out.Pinned = theirs.Pinned
+	}
+	deletedAt := func(p Project) string {
+		if p.DeletedAt == nil {
+			return ""
+		}
+		return p.DeletedAt.UTC().Format(time.RFC3339Nano)
+	}
+	switch {
+	case deletedAt(ours) == deletedAt(base):
+		out.DeletedAt = theirs.DeletedAt
+	case deletedAt(theirs) != deletedAt(base) && theirsNewer:
+		out.DeletedAt = theirs.DeletedAt
+	}
+
+	// Opens since base happened on one side or the other, so both count.
+	out.OpenCount = ours.OpenCount + theirs.OpenCount - base.OpenCount
+	if out.OpenCount < max(ours.OpenCount, theirs.OpenCount) {
+		out.OpenCount = max(ours.OpenCount, theirs.OpenCount)
+	}
+	seen := make(map[time.Time]bool)
+	var opens []time.Time
+	for _, t := range append(append([]time.Time{}, ours.Opens...), theirs.Opens...) {
+		if !seen[t.UTC()] {
+			seen[t.UTC()] = true
+			opens = append(opens, t)
+		}
+	}
+	sort.Slice(opens, func(i, j int) bool { return opens[i].Before(opens[j]) })
+	if len(opens) > maxRecordedOpens {
+		opens = opens[len(opens)-maxRecordedOpens:]
+	}
+	out.Opens = opens
+	return out
+}
+
+// mergeTags keeps ours in order, adds the tags theirs added and drops the
+// ones theirs removed.
+func mergeTags(base, ours, theirs []string) []string {
+	has := func(tags []string, tag string) bool {
+		for _, t := range tags {
+			if strings.EqualFold(t, tag) {
+				return true
+			}
+		}
+		return false
+	}
+	var out []string
+	for _, t := range ours {
+		if has(theirs, t) || !has(base, t) {
+			out = append(out, t)
+		}
+	}
+	for _, t := range theirs {
+		if !has(ours, t) && !has(base, t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// onlyOpensChanged reports whether after is before with nothing but open
+// history recorded, which isn't worth a backup.
+func onlyOpensChanged(before, after []Project) bool {
+	if len(before) != len(after) {
+		return false
+	}
+	for i := range after {
+		if !sameExceptOpens(before[i], after[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func backupPath(path string, n int) string {
+	return fmt.Sprintf("%s.bak.%d", path, n)
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory,
+// syncs it and renames it over path, so readers see either the old or the
+// new contents but never a partial write.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // no-op once the rename succeeded
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return err
+	}
+
+	// Persist the rename itself. Not every platform supports syncing a
+	// directory, so failures here are ignored.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// rotateBackups shifts existing backups down by one and copies the current
+// contents of path into .bak.1. Files that don't parse are not backed up so
+// that a corrupt store never pushes good versions out of the rotation.
+func rotateBackups(path string, keep int) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if _, err := parseProjects(data); err != nil {
+		return nil
+	}
+
+	for n := keep - 1; n >= 1; n-- {
+		err := os.Rename(backupPath(path, n), backupPath(path, n+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return writeFileAtomic(backupPath(path, 1), data, 0o644)
+}
+
+// parseProjects decodes the contents of a projects file of any version,
+// upgrading it to the current one in memory.
+func parseProjects(data []byte) ([]Project, error) {
+	projects, version, err := decodeStore(data)
+	if err != nil {
+		return nil, err
+	}
+	if projects, err = migrate(projects, version); err != nil {
+		return nil, err
+	}
+	// Hand edits may leave IDs out or copy them.
+	return assignIDs(projects), nil
+}
+
+// latestValidBackup returns the newest backup of path that parses.
+func latestValidBackup(path string) (string, error) {
+	for n := 1; n <= maxBackups; n++ {
+		b := backupPath(path, n)
+		data, err := os.ReadFile(b)
+		if err != nil {
+			continue
+		}
+		if _, err := parseProjects(data); err == nil {
+			return b, nil
+		}
+	}
+	return "", errors.New("no valid backup found")
+}
+
+// restoreBackup replaces the store with the given backup. The file being
+// replaced is kept as projects.json.corrupt-<timestamp> for manual recovery.
+func restoreBackup(path, backup string) error {
+	data, err := os.ReadFile(backup)
+	if err != nil {
+		return err
+	}
+	if _, err := parseProjects(data); err != nil {
+		return fmt.Errorf("%s: %w", backup, err)
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		aside := fmt.Sprintf("%s.corrupt-%s", path, time.Now().Format("20060102-150405"))
+		if err := os.Rename(path, aside); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(path, data, 0o644)
+}
@@ -0,0 +1,466 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+var (
+	t0 = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	t1 = t0.Add(time.Hour)
+	t2 = t0.Add(2 * time.Hour)
+)
+
+// testProject returns a project created at the given time, with the ID the
+// version 2 migration would give it.
+func testProject(name string, created time.Time) Project {
+	return Project{ID: stableID(created), Name: name, Path: "/src/" + name, CreatedAt: created, UpdatedAt: created}
+}
+
+func withDescription(p Project, desc string, at time.Time) Project {
+	p.Description = desc
+	p.UpdatedAt = at
+	return p
+}
+
+// testStore returns a model backed by a projects file in a new temporary
+// directory.
+func testStore(t *testing.T) model {
+	t.Helper()
+	m := model{projectsFile: filepath.Join(t.TempDir(), "projects.json")}
+	if err := m.loadProjects(); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// indexOfName returns the index of the project called name.
+func indexOfName(t *testing.T, m *model, name string) int {
+	t.Helper()
+	for i, p := range m.projects {
+		if p.Name == name {
+			return i
+		}
+	}
+	t.Fatalf("no project called %q", name)
+	return -1
+}
+
+// storeWith returns a model whose store holds projects.
+func storeWith(t *testing.T, projects ...Project) model {
+	t.Helper()
+	m := testStore(t)
+	m.projects = projects
+	if err := m.saveProjects(); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// saveN saves n times, adding a project before each save.
+func saveN(t *testing.T, m *model, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		m.projects = append(m.projects, Project{Name: fmt.Sprintf("p%d", len(m.projects)), Path: "/src"})
+		if err := m.saveProjects(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// backupsOf returns the numbers of the backups of path that exist.
+func backupsOf(path string) []int {
+	var ns []int
+	for n := 1; n <= maxBackups+1; n++ {
+		if _, err := os.Stat(backupPath(path, n)); err == nil {
+			ns = append(ns, n)
+		}
+	}
+	return ns
+}
+
+func TestRotateBackups(t *testing.T) {
+	tests := []struct {
+		saves, wantBackups int
+	}{
+		{1, 0},
+		{2, 1},
+		{maxBackups, maxBackups - 1},
+		{maxBackups + 1, maxBackups},
+		{maxBackups + 4, maxBackups},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d saves", tt.saves), func(t *testing.T) {
+			m := testStore(t)
+			saveN(t, &m, tt.saves)
+			if got := backupsOf(m.projectsFile); len(got) != tt.wantBackups {
+				t.Fatalf("backups %v, want %d", got, tt.wantBackups)
+			}
+			// Each backup holds one project fewer than the version after it.
+			for n := 1; n <= tt.wantBackups; n++ {
+				data, err := os.ReadFile(backupPath(m.projectsFile, n))
+				if err != nil {
+					t.Fatal(err)
+				}
+				ps, err := parseProjects(data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := tt.saves - n; len(ps) != want {
+					t.Errorf(".bak.%d has %d projects, want %d", n, len(ps), want)
+				}
+			}
+		})
+	}
+}
+
+func TestOpensDontRotateBackups(t *testing.T) {
+	m := testStore(t)
+	saveN(t, &m, 3)
+	backup, _ := os.ReadFile(backupPath(m.projectsFile, 1))
+
+	for i := 0; i < maxBackups+1; i++ {
+		if err := m.markOpened(indexOfName(t, &m, "p0")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := backupsOf(m.projectsFile); len(got) != 2 {
+		t.Errorf("backups %v after opening, want the 2 from before", got)
+	}
+	if got, _ := os.ReadFile(backupPath(m.projectsFile, 1)); string(got) != string(backup) {
+		t.Error("opening replaced .bak.1")
+	}
+	saved := model{projectsFile: m.projectsFile}
+	if err := saved.loadProjects(); err != nil {
+		t.Fatal(err)
+	}
+	if got := saved.projects[indexOfName(t, &saved, "p0")].OpenCount; got != maxBackups+1 {
+		t.Errorf("open count %d, want %d", got, maxBackups+1)
+	}
+
+	// Any other change is backed up again, even alongside an open.
+	m.projects[0].recordOpen(t0)
+	m.projects[0].Description = "edited"
+	if err := m.saveProjects(); err != nil {
+		t.Fatal(err)
+	}
+	if got := backupsOf(m.projectsFile); len(got) != 3 {
+		t.Errorf("backups %v after an edit, want 3", got)
+	}
+}
+
+func TestRotateBackupsSkipsCorruptStore(t *testing.T) {
+	m := testStore(t)
+	saveN(t, &m, 2)
+	if err := os.WriteFile(m.projectsFile, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := rotateBackups(m.projectsFile, maxBackups); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(backupPath(m.projectsFile, 1))
+	if _, err := parseProjects(data); err != nil {
+		t.Errorf("the corrupt store was rotated into .bak.1: %v", err)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "projects.json")
+	for _, content := range []string{"first", "second"} {
+		if err := writeFileAtomic(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := os.ReadFile(path); string(got) != content {
+			t.Errorf("file holds %q, want %q", got, content)
+		}
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("mode %v, want 0600", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestCorruptStore(t *testing.T) {
+	m := testStore(t)
+	saveN(t, &m, 3)
+	if err := os.WriteFile(m.projectsFile, []byte("[{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// A corrupt newest backup is passed over.
+	if err := os.WriteFile(backupPath(m.projectsFile, 1), []byte("garbage"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var corrupt *corruptStoreError
+	if err := m.loadProjects(); !errors.As(err, &corrupt) {
+		t.Fatalf("loadProjects() error = %v, want a corruptStoreError", err)
+	}
+	if want := backupPath(m.projectsFile, 2); corrupt.backup != want {
+		t.Errorf("backup = %q, want %q", corrupt.backup, want)
+	}
+	if err := m.saveProjects(); err == nil {
+		t.Error("saveProjects() overwrote a corrupt store")
+	}
+
+	if err := m.recoverFromBackup(corrupt.backup); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.projects) != 1 {
+		t.Errorf("recovered %d projects, want 1", len(m.projects))
+	}
+	aside, _ := filepath.Glob(m.projectsFile + ".corrupt-*")
+	if len(aside) != 1 {
+		t.Errorf("corrupt store kept as %v, want one file", aside)
+	}
+}
+
+func TestMergeProjects(t *testing.T) {
+	a, b, c := testProject("api", t0), testProject("blog", t1), testProject("cli", t2)
+	bEdited := withDescription(b, "edited", t1)
+	aOurs := withDescription(a, "ours", t1)
+	aTheirs := withDescription(a, "theirs", t2)
+
+	tests := []struct {
+		name               string
+		base, ours, theirs []Project
+		want               []Project
+	}{
+		{"unchanged", []Project{a, b}, []Project{a, b}, []Project{a, b}, []Project{a, b}},
+		{"added by us goes first", []Project{a}, []Project{c, a}, []Project{a}, []Project{c, a}},
+		{"added elsewhere", []Project{a}, []Project{a}, []Project{a, c}, []Project{a, c}},
+		{"added on both sides", nil, []Project{a}, []Project{a}, []Project{a}},
+		{"deleted by us", []Project{a, b}, []Project{a}, []Project{a, b}, []Project{a}},
+		{"deleted elsewhere", []Project{a, b}, []Project{a, b}, []Project{a}, []Project{a}},
+		{"deleted by us, edited elsewhere", []Project{a, b}, []Project{a}, []Project{a, bEdited}, []Project{a, bEdited}},
+		{"deleted elsewhere, edited by us", []Project{a, b}, []Project{a, bEdited}, []Project{a}, []Project{a, bEdited}},
+		{"edited by us", []Project{a}, []Project{aOurs}, []Project{a}, []Project{aOurs}},
+		{"edited elsewhere", []Project{a}, []Project{a}, []Project{aTheirs}, []Project{aTheirs}},
+		{"edited on both sides goes to the newer edit", []Project{a}, []Project{aOurs}, []Project{aTheirs}, []Project{aTheirs}},
+		{"empty", nil, nil, nil, []Project{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeProjects(tt.base, tt.ours, tt.theirs)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d projects %v, want %d", len(got), got, len(tt.want))
+			}
+			for i := range got {
+				if !sameProject(got[i], tt.want[i]) {
+					t.Errorf("project %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMergeProject(t *testing.T) {
+	base := testProject("api", t0)
+	edit := func(at time.Time, change func(*Project)) Project {
+		p := base
+		p.UpdatedAt = at
+		change(&p)
+		return p
+	}
+	deleted := t1
+
+	tests := []struct {
+		name         string
+		ours, theirs Project
+		check        func(Project) bool
+	}{
+		{
+			"different fields are both kept",
+			edit(t1, func(p *Project) { p.Name = "api-v2" }),
+			edit(t2, func(p *Project) { p.Path = "/work/api" }),
+			func(p Project) bool { return p.Name == "api-v2" && p.Path == "/work/api" && p.UpdatedAt.Equal(t2) },
+		},
+		{
+			"same field goes to the newer edit",
+			edit(t1, func(p *Project) { p.Description = "ours" }),
+			edit(t2, func(p *Project) { p.Description = "theirs" }),
+			func(p Project) bool { return p.Description == "theirs" },
+		},
+		{
+			"same field, ours newer",
+			edit(t2, func(p *Project) { p.Opener = "vim" }),
+			edit(t1, func(p *Project) { p.Opener = "code" }),
+			func(p Project) bool { return p.Opener == "vim" && p.UpdatedAt.Equal(t2) },
+		},
+		{
+			"ties go to ours",
+			edit(t1, func(p *Project) { p.Remote = "git@a" }),
+			edit(t1, func(p *Project) { p.Remote = "git@b" }),
+			func(p Project) bool { return p.Remote == "git@a" },
+		},
+		{
+			"tags are merged as a set",
+			edit(t1, func(p *Project) { p.Tags = []string{"go"} }),
+			edit(t2, func(p *Project) { p.Tags = []string{"web"} }),
+			func(p Project) bool { return slices.Equal(p.Tags, []string{"go", "web"}) },
+		},
+		{
+			"pinned elsewhere",
+			edit(t1, func(p *Project) { p.Description = "ours" }),
+			edit(t1, func(p *Project) { p.Pinned = 2 }),
+			func(p Project) bool { return p.Pinned == 2 && p.Description == "ours" },
+		},
+		{
+			"trashed elsewhere",
+			edit(t2, func(p *Project) { p.Description = "ours" }),
+			edit(t1, func(p *Project) { p.DeletedAt = &deleted }),
+			func(p Project) bool { return p.DeletedAt != nil && p.DeletedAt.Equal(deleted) },
+		},
+		{
+			"opens on both sides add up",
+			edit(t0, func(p *Project) { p.OpenCount, p.Opens = 1, []time.Time{t1} }),
+			edit(t0, func(p *Project) { p.OpenCount, p.Opens = 2, []time.Time{t2, t0} }),
+			func(p Project) bool {
+				return p.OpenCount == 3 && slices.EqualFunc(p.Opens, []time.Time{t0, t1, t2}, time.Time.Equal)
+			},
+		},
+		{
+			"the same open counts once",
+			edit(t0, func(p *Project) { p.OpenCount, p.Opens = 1, []time.Time{t1} }),
+			edit(t0, func(p *Project) { p.OpenCount, p.Opens = 1, []time.Time{t1} }),
+			func(p Project) bool { return p.OpenCount == 2 && len(p.Opens) == 1 },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeProject(base, tt.ours, tt.theirs); !tt.check(got) {
+				t.Errorf("mergeProject() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	tests := []struct {
+		name               string
+		base, ours, theirs []string
+		want               []string
+	}{
+		{"unchanged", []string{"go"}, []string{"go"}, []string{"go"}, []string{"go"}},
+		{"added on both sides", []string{"go"}, []string{"go", "cli"}, []string{"go", "web"}, []string{"go", "cli", "web"}},
+		{"removed elsewhere", []string{"go", "cli"}, []string{"go", "cli"}, []string{"go"}, []string{"go"}},
+		{"removed by us", []string{"go", "cli"}, []string{"go"}, []string{"go", "cli"}, []string{"go"}},
+		{"added on both sides in another case", nil, []string{"Go"}, []string{"go"}, []string{"Go"}},
+		{"removed everywhere", []string{"go"}, nil, nil, nil},
+		{"keeps our order", nil, []string{"b", "a"}, []string{"a", "c"}, []string{"b", "a", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeTags(tt.base, tt.ours, tt.theirs); !slices.Equal(got, tt.want) {
+				t.Errorf("mergeTags(%v, %v, %v) = %v, want %v", tt.base, tt.ours, tt.theirs, got, tt.want)
+			}
+		})
+	}
+}
+
+// twoStores returns two models that loaded the same projects file, as two
+// running phonebooks would.
+func twoStores(t *testing.T, projects ...Project) (model, model) {
+	t.Helper()
+	a := testStore(t)
+	a.projects = projects
+	if err := a.saveProjects(); err != nil {
+		t.Fatal(err)
+	}
+	b := model{projectsFile: a.projectsFile}
+	if err := b.loadProjects(); err != nil {
+		t.Fatal(err)
+	}
+	return a, b
+}
+
+func TestSaveMergesConcurrentChanges(t *testing.T) {
+	api, blog := testProject("api", t0), testProject("blog", t1)
+	a, b := twoStores(t, api, blog)
+
+	a.projects = append(a.projects, testProject("cli", t2))
+	if err := a.saveProjects(); err != nil {
+		t.Fatal(err)
+	}
+	if !b.changedOnDisk() {
+		t.Error("changedOnDisk() = false after another save")
+	}
+
+	// b edits one project and deletes the other without reloading.
+	b.projects = []Project{withDescription(api, "edited", t2)}
+	if err := b.saveProjects(); err != nil {
+		t.Fatal(err)
+	}
+	if b.changedOnDisk() {
+		t.Error("changedOnDisk() = true after our own save")
+	}
+
+	c := model{projectsFile: a.projectsFile}
+	if err := c.loadProjects(); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, p := range c.projects {
+		got[p.Name] = p.Description
+	}
+	want := map[string]string{"api": "edited", "cli": ""}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("saved projects %v, want %v", got, want)
+	}
+}
+
+func TestSaveRefusesUnreadableChanges(t *testing.T) {
+	a, b := twoStores(t, testProject("api", t0))
+	if err := os.WriteFile(a.projectsFile, []byte("{oops"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	b.projects = nil
+	if err := b.saveProjects(); err == nil {
+		t.Error("saveProjects() overwrote a store changed into something unreadable")
+	}
+}
+
+func TestAddProjects(t *testing.T) {
+	existing := testProject("api", t0)
+	m := storeWith(t, existing)
+
+	imported := testProject("blog", t1)
+	taken := testProject("cli", t2)
+	taken.ID = existing.ID
+	err := m.addProjects([]Project{
+		imported,
+		taken,
+		{Name: "new1", Path: "/src/new1"},
+		{Name: "new2", Path: "/src/new2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byName := make(map[string]Project)
+	ids := make(map[string]bool)
+	for _, p := range m.projects {
+		byName[p.Name] = p
+		if ids[p.ID] {
+			t.Errorf("ID %s is used twice", p.ID)
+		}
+		ids[p.ID] = true
+	}
+	if got := byName["blog"]; !got.CreatedAt.Equal(t1) || got.ID != imported.ID {
+		t.Errorf("imported project created %v with ID %s, want %v and %s", got.CreatedAt, got.ID, t1, imported.ID)
+	}
+	if got := byName["cli"]; !got.CreatedAt.Equal(t2) || got.ID == existing.ID {
+		t.Errorf("project with a taken ID created %v with ID %s, want %v and a new ID", got.CreatedAt, got.ID, t2)
+	}
+	new1, new2 := byName["new1"].CreatedAt, byName["new2"].CreatedAt
+	if new1.IsZero() || !new2.After(new1) {
+		t.Errorf("new projects created %v and %v, want distinct times in order", new1, new2)
+	}
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// syncTimeout bounds git commands that talk to the sync remote.
+const syncTimeout = 60 * time.Second
+
+// syncRemote is the git remote sync pulls from and pushes to.
+const syncRemote = "origin"
+
+// syncIgnore keeps everything but the phonebooks out of the sync repository:
+// backups, locks and a config.json left by older versions belong to this machine.
+const syncIgnore = `# Written by phonebook sync. Only the phonebooks themselves are synced.
+/*
+!/.gitignore
+!/projects.json
+!/books/
+/books/*
+!/books/*.json
+`
+
+// errSyncNotSetUp is returned by sync commands before sync init has run.
+var errSyncNotSetUp = errors.New("sync is not set up; run 'phonebook sync init [remote]'")
+
+// syncEnabled reports whether the store directory is a sync repository.
+func syncEnabled() bool {
+	_, err := os.Stat(filepath.Join(storeDir(), ".git"))
+	return err == nil
+}
+
+// lockSync serializes git operations on the sync repository between
+// processes. The lock file lives inside .git so it is never committed.
+func lockSync() (func(), error) {
+	return lockFile(filepath.Join(storeDir(), ".git", "phonebook"))
+}
+
+func hostname() string {
+	if h, err := os.Hostname(); err == nil {
+		return h
+	}
+	return "unknown host"
+}
+
+// isBookPath reports whether a path in the sync repository is a phonebook.
+func isBookPath(rel string) bool {
+	rel = filepath.ToSlash(rel)
+	if rel == "projects.json" {
+		return true
+	}
+	dir, name := filepath.Split(rel)
+	return dir == "books/" && strings.HasSuffix(name, ".json")
+}
+
+// commitStore commits a phonebook that was just saved, if sync is set up.
+// There is nothing to commit when the save didn't change the file.
+func commitStore(file, book string) error {
+	if !syncEnabled() {
+		return nil
+	}
+	dir := storeDir()
+	rel, err := filepath.Rel(dir, file)
+	if err != nil || !isBookPath(rel) {
+		return nil
+	}
+	unlock, err := lockSync()
+	if err != nil {
+		return fmt.Errorf("locking sync repository: %w", err)
+	}
+	defer unlock()
+
+	if _, err := runGit(dir, "add", "--", rel); err != nil {
+		return fmt.Errorf("committing %s: %w", rel, err)
+	}
+	if _, err := runGit(dir, "diff", "--cached", "--quiet", "--", rel); err == nil {
+		return nil
+	}
+	msg := fmt.Sprintf("Update %s phonebook on %s", book, hostname())
+	if _, err := runGit(dir, "commit", "-q", "-m", msg, "--", rel); err != nil {
+		return fmt.Errorf("committing %s: %w", rel, err)
+	}
+	return nil
+}
+
+// commitPending commits phonebook changes made without saveProjects, such
+// as a restore or a hand edit. The caller holds the sync lock.
+func commitPending(dir string) error {
+	if _, err := runGit(dir, "add", "-A"); err != nil {
+		return err
+	}
+	if _, err := runGit(dir, "diff", "--cached", "--quiet"); err == nil {
+		return nil
+	}
+	_, err := runGit(dir, "commit", "-q", "-m", "Record changes on "+hostname())
+	return err
+}
+
+// initSync turns the store directory into a sync repository, or updates the
+// remote of an existing one, and commits what is there.
+func initSync(remote string) error {
+	dir := storeDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if !syncEnabled() {
+		if _, err := runGit(dir, "init", "-q", "-b", "main"); err != nil {
+			return err
+		}
+	}
+	unlock, err := lockSync()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	ignore := filepath.Join(dir, ".gitignore")
+	if _, err := os.Stat(ignore); os.IsNotExist(err) {
+		if err := os.WriteFile(ignore, []byte(syncIgnore), 0o644); err != nil {
+			return err
+		}
+	}
+	// Commits need an author; fall back to one naming this machine rather
+	// than failing on a fresh install.
+	if out, _ := runGit(dir, "config", "user.email"); strings.TrimSpace(out) == "" {
+		runGit(dir, "config", "user.name", "phonebook")
+		runGit(dir, "config", "user.email", "phonebook@"+hostname())
+	}
+	if remote != "" {
+		if _, err := runGit(dir, "remote", "get-url", syncRemote); err == nil {
+			_, err = runGit(dir, "remote", "set-url", syncRemote, remote)
+			if err != nil {
+				return err
+			}
+		} else if _, err := runGit(dir, "remote", "add", syncRemote, remote); err != nil {
+			return err
+		}
+	}
+	return commitPending(dir)
+}
+
+// syncResult summarizes what a sync did.
+type syncResult struct {
+	pulled int  // commits taken from the remote
+	merged bool // both sides had changes and were merged
+	pushed int  // commits sent to the remote
+}
+
+func (r syncResult) String() string {
+	if r.pulled == 0 && r.pushed == 0 {
+		return "Already up to date"
+	}
+	var parts []string
+	if r.pulled > 0 {
+		parts = append(parts, "pulled "+plural(r.pulled, "commit"))
+	}
+	if r.merged {
+		parts = append(parts, "merged")
+	}
+	if r.pushed > 0 {
+		parts = append(parts, "pushed "+plural(r.pushed, "commit"))
+	}
+	s := strings.Join(parts, ", ")
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// runSync commits pending changes, pulls the remote's commits, merges them
+// project by project and pushes the result. Git never sees a textual
+// conflict: when both sides changed, the phonebooks are merged with
+// mergeProjects and recorded as a merge commit.
+func runSync() (syncResult, error) {
+	var res syncResult
+	if !syncEnabled() {
+		return res, errSyncNotSetUp
+	}
+	dir := storeDir()
+	unlock, err := lockSync()
+	if err != nil {
+		return res, fmt.Errorf("locking sync repository: %w", err)
+	}
+	defer unlock()
+
+	if err := commitPending(dir); err != nil {
+		return res, fmt.Errorf("committing local changes: %w", err)
+	}
+	if _, err := runGit(dir, "remote", "get-url", syncRemote); err != nil {
+		return res, fmt.Errorf("no sync remote; add one with 'phonebook sync init <remote>'")
+	}
+	out, err := runGit(dir, "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return res, err
+	}
+	branch := strings.TrimSpace(out)
+	if _, err := runGitTimeout(syncTimeout, dir, "fetch", "-q", syncRemote); err != nil {
+		return res, fmt.Errorf("fetching from %s: %w", syncRemote, err)
+	}
+	// The fetch can take a while; commit what was saved in the meantime.
+	if err := commitPending(dir); err != nil {
+		return res, fmt.Errorf("committing local changes: %w", err)
+	}
+
+	theirs := syncRemote + "/" + branch
+	if _, err := runGit(dir, "rev-parse", "--verify", "-q", theirs); err == nil {
+		if res.pulled, err = countCommits(dir, "HEAD.."+theirs); err != nil {
+			return res, err
+		}
+		if res.pulled > 0 {
+			if res.merged, err = pullSync(dir, theirs); err != nil {
+				return res, err
+			}
+		}
+		if res.pushed, err = countCommits(dir, theirs+"..HEAD"); err != nil {
+			return res, err
+		}
+	} else if res.pushed, err = countCommits(dir, "HEAD"); err != nil {
+		return res, err
+	}
+
+	if res.pushed > 0 {
+		_, err := runGitTimeout(syncTimeout, dir, "push", "-q", syncRemote, "HEAD:refs/heads/"+branch)
+		if err != nil {
+			return res, fmt.Errorf("pushing to %s: %w", syncRemote, err)
+		}
+	}
+	return res, nil
+}
+
+func countCommits(dir, revs string) (int, error) {
+	out, err := runGit(dir, "rev-list", "--count", revs)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(out))
+}
+
+// pullSync brings theirs into HEAD. A fast-forward is left to git; diverged
+// histories are merged by pullMerge. It reports whether a merge was needed.
+func pullSync(dir, theirs string) (bool, error) {
+	if _, err := runGit(dir, "merge-base", "--is-ancestor", "HEAD", theirs); err == nil {
+		if _, err := runGit(dir, "merge", "-q", "--ff-only", theirs); err != nil {
+			return false, fmt.Errorf("updating from %s: %w", theirs, err)
+		}
+		return false, nil
+	}
+	return true, pullMerge(dir, theirs)
+}
+
+// pullMerge records a merge of theirs whose phonebooks are merged by
+// mergeProjects. Git's own merge would only see conflicting lines of JSON.
+func pullMerge(dir, theirs string) error {
+	// Repositories set up separately on two machines share no history; the
+	// merge then starts from empty phonebooks.
+	base := ""
+	if out, err := runGit(dir, "merge-base", "HEAD", theirs); err == nil {
+		base = strings.TrimSpace(out)
+	}
+
+	files := make(map[string]bool)
+	for _, rev := range []string{"HEAD", theirs} {
+		names, err := bookPaths(dir, rev)
+		if err != nil {
+			return err
+		}
+		for name := range names {
+			files[name] = true
+		}
+	}
+
+	merged := make(map[string][]Project, len(files))
+	for rel := range files {
+		var sides [3][]Project
+		for i, rev := range []string{base, "HEAD", theirs} {
+			ps, err := readBookAt(dir, rev, rel)
+			if err != nil {
+				return err
+			}
+			sides[i] = ps
+		}
+		merged[rel] = mergeProjects(sides[0], sides[1], sides[2])
+	}
+
+	args := []string{"merge", "-q", "--no-ff", "--no-commit", "-s", "ours"}
+	if base == "" {
+		args = append(args, "--allow-unrelated-histories")
+	}
+	if _, err := runGit(dir, append(args, theirs)...); err != nil {
+		return fmt.Errorf("merging %s: %w", theirs, err)
+	}
+	for rel, projects := range merged {
+		if err := writeBook(dir, rel, projects); err != nil {
+			runGit(dir, "merge", "--abort")
+			return err
+		}
+	}
+	if _, err := runGit(dir, "add", "-A"); err != nil {
+		runGit(dir, "merge", "--abort")
+		return err
+	}
+	msg := fmt.Sprintf("Merge phonebooks from %s on %s", theirs, hostname())
+	if _, err := runGit(dir, "commit", "-q", "-m", msg); err != nil {
+		runGit(dir, "merge", "--abort")
+		return err
+	}
+	return nil
+}
+
+// bookPaths lists the phonebooks committed in rev.
+func bookPaths(dir, rev string) (map[string]bool, error) {
+	out, err := runGit(dir, "ls-tree", "-r", "--name-only", rev)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for _, name := range strings.Split(strings.TrimSpace(out), "\n") {
+		if isBookPath(name) {
+			names[name] = true
+		}
+	}
+	return names, nil
+}
+
+// readBookAt returns the projects in the phonebook rel as of rev. A rev of ""
+// or one without the file gives an empty phonebook.
+func readBookAt(dir, rev, rel string) ([]Project, error) {
+	if rev == "" {
+		return nil, nil
+	}
+	names, err := bookPaths(dir, rev)
+	if err != nil || !names[rel] {
+		return nil, err
+	}
+	out, err := runGit(dir, "show", rev+":"+rel)
+	if err != nil {
+		return nil, err
+	}
+	ps, err := parseProjects([]byte(out))
+	if err != nil {
+		return nil, fmt.Errorf("%s in %s: %w", rel, rev, err)
+	}
+	return ps, nil
+}
+
+// writeBook replaces the phonebook rel with merged projects the way
+// saveProjects does: under its lock, with a backup of what was there. A save
+// made after the last commit, which the merge didn't see, is folded in.
+func writeBook(dir, rel string, merged []Project) error {
+	path := filepath.Join(dir, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	unlock, err := lockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		committed, err := readBookAt(dir, "HEAD", rel)
+		if err != nil {
+			return err
+		}
+		saved, err := parseProjects(current)
+		if err != nil {
+			return fmt.Errorf("%s: %w", rel, err)
+		}
+		merged = mergeProjects(committed, saved, merged)
+	}
+	result := model{projects: merged}
+	// Both sides may have pinned something at the same position.
+	result.renumberPins(result.pinOrder())
+	data, err := encodeStore(result.projects)
+	if err != nil {
+		return err
+	}
+
+	if err := rotateBackups(path, maxBackups); err != nil {
+		return fmt.Errorf("backing up %s: %w", filepath.Base(path), err)
+	}
+	return writeFileAtomic(path, data, 0o644)
+}
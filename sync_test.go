@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// machine is one computer taking part in a sync, told apart by its home
+// directory.
+type machine struct {
+	t    *testing.T
+	home string
+}
+
+func newMachine(t *testing.T) *machine {
+	return &machine{t: t, home: t.TempDir()}
+}
+
+// load makes m the current machine and loads its default phonebook.
+func (m *machine) load() model {
+	m.t.Helper()
+	m.t.Setenv("HOME", m.home)
+	book := newModel()
+	if err := book.load(); err != nil {
+		m.t.Fatal(err)
+	}
+	return book
+}
+
+// sync makes m the current machine and syncs it.
+func (m *machine) sync() syncResult {
+	m.t.Helper()
+	m.t.Setenv("HOME", m.home)
+	res, err := runSync()
+	if err != nil {
+		m.t.Fatalf("sync: %v", err)
+	}
+	return res
+}
+
+// byName returns the projects in book keyed by name.
+func byName(book model) map[string]Project {
+	out := make(map[string]Project)
+	for _, p := range book.projects {
+		out[p.Name] = p
+	}
+	return out
+}
+
+func TestSync(t *testing.T) {
+	withHome(t)
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	if _, err := runGit(filepath.Dir(remote), "init", "-q", "--bare", "-b", "main", remote); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first machine sets up sync with the projects it already has.
+	a := newMachine(t)
+	book := a.load()
+	for _, p := range []Project{testProject("api", t0), testProject("blog", t1), testProject("cli", t2)} {
+		if err := book.addProject(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := initSync(remote); err != nil {
+		t.Fatal(err)
+	}
+	if res := a.sync(); res.pushed != 1 {
+		t.Errorf("first sync pushed %d commits, want 1", res.pushed)
+	}
+
+	// The second machine clones the sync repository.
+	b := newMachine(t)
+	t.Setenv("HOME", b.home)
+	if err := os.MkdirAll(filepath.Dir(storeDir()), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runGit(filepath.Dir(storeDir()), "clone", "-q", remote, filepath.Base(storeDir())); err != nil {
+		t.Fatal(err)
+	}
+	if err := initSync(""); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(b.load().projects); got != 3 {
+		t.Fatalf("the clone holds %d projects, want 3", got)
+	}
+
+	// Both machines change the phonebook before syncing again.
+	book = a.load()
+	api := book.projects[indexOfName(t, &book, "api")]
+	api.Description = "edited on a"
+	if err := book.updateProject(indexOfName(t, &book, "api"), api); err != nil {
+		t.Fatal(err)
+	}
+	if err := book.addProject(testProject("web", t0.Add(3*time.Hour))); err != nil {
+		t.Fatal(err)
+	}
+
+	book = b.load()
+	blog := book.projects[indexOfName(t, &book, "blog")]
+	blog.Tags = []string{"writing"}
+	if err := book.updateProject(indexOfName(t, &book, "blog"), blog); err != nil {
+		t.Fatal(err)
+	}
+	if err := book.deleteProject(indexOfName(t, &book, "cli")); err != nil {
+		t.Fatal(err)
+	}
+
+	if res := a.sync(); res.pulled != 0 || res.pushed != 2 {
+		t.Errorf("a's sync = %+v, want 2 commits pushed", res)
+	}
+	if res := b.sync(); !res.merged || res.pulled != 2 || res.pushed != 3 {
+		t.Errorf("b's sync = %+v, want 2 commits pulled, merged and 3 pushed", res)
+	}
+	if res := a.sync(); res.merged || res.pulled != 3 {
+		t.Errorf("a's second sync = %+v, want 3 commits pulled without a merge", res)
+	}
+	if res := a.sync(); res.String() != "Already up to date" {
+		t.Errorf("a's third sync = %q, want nothing to do", res)
+	}
+
+	// The remote's history records the merge, and both machines end up with
+	// every change, the trashing of cli included.
+	out, err := runGit(remote, "rev-list", "--parents", "-n", "1", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parents := len(strings.Fields(out)) - 1; parents != 2 {
+		t.Errorf("remote head has %d parents, want a merge", parents)
+	}
+	atRemote, err := readBookAt(remote, "main", "projects.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, m := range map[string]*machine{"a": a, "b": b} {
+		got := byName(m.load())
+		if len(got) != 4 {
+			t.Errorf("%s holds %d projects, want 4", name, len(got))
+		}
+		if got["api"].Description != "edited on a" {
+			t.Errorf("%s: api description %q, want a's edit", name, got["api"].Description)
+		}
+		if len(got["blog"].Tags) != 1 || got["blog"].Tags[0] != "writing" {
+			t.Errorf("%s: blog tags %v, want b's edit", name, got["blog"].Tags)
+		}
+		if !got["cli"].archived() {
+			t.Errorf("%s: cli is not in the trash", name)
+		}
+		if _, ok := got["web"]; !ok {
+			t.Errorf("%s: web is missing", name)
+		}
+	}
+	if len(atRemote) != 4 {
+		t.Errorf("the remote holds %d projects, want 4", len(atRemote))
+	}
+}
+
+func TestReadBookAt(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := runGit(dir, "init", "-q", "-b", "main"); err != nil {
+		t.Fatal(err)
+	}
+	write := func(rel, content string) {
+		path := filepath.Join(dir, rel)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("projects.json", `[{"name": "api", "path": "/src/api"}]`)
+	write("books/work.json", `{oops`)
+	write("notes.json", `[]`)
+	runGit(dir, "add", "-A")
+	if _, err := runGit(dir, "-c", "user.name=t", "-c", "user.email=t@t", "commit", "-q", "-m", "c"); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := bookPaths(dir, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || !names["projects.json"] || !names["books/work.json"] {
+		t.Errorf("bookPaths() = %v, want projects.json and books/work.json", names)
+	}
+	if ps, err := readBookAt(dir, "HEAD", "projects.json"); err != nil || len(ps) != 1 {
+		t.Errorf("readBookAt(projects.json) = %v, %v; want one project", ps, err)
+	}
+	if ps, err := readBookAt(dir, "", "projects.json"); err != nil || ps != nil {
+		t.Errorf("readBookAt with no rev = %v, %v; want an empty book", ps, err)
+	}
+	if ps, err := readBookAt(dir, "HEAD", "books/home.json"); err != nil || ps != nil {
+		t.Errorf("readBookAt of a missing book = %v, %v; want an empty book", ps, err)
+	}
+	if _, err := readBookAt(dir, "HEAD", "books/work.json"); err == nil {
+		t.Error("readBookAt of a corrupt book gave no error")
+	}
+}
+
+func TestWriteBookKeepsUncommittedSaves(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := runGit(dir, "init", "-q", "-b", "main"); err != nil {
+		t.Fatal(err)
+	}
+	api, blog := testProject("api", t0), testProject("blog", t1)
+	path := filepath.Join(dir, "projects.json")
+	write := func(ps ...Project) {
+		data, err := encodeStore(ps)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(api, blog)
+	runGit(dir, "add", "-A")
+	if _, err := runGit(dir, "-c", "user.name=t", "-c", "user.email=t@t", "commit", "-q", "-m", "c"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A save lands while the merge is worked out from the committed book.
+	write(api, blog, testProject("cli", t2))
+	merged := []Project{withDescription(api, "merged", t2), blog}
+	if err := writeBook(dir, "projects.json", merged); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	ps, err := parseProjects(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, p := range ps {
+		got[p.Name] = p.Description
+	}
+	if want := map[string]string{"api": "merged", "blog": "", "cli": ""}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("book holds %v, want %v", got, want)
+	}
+	if _, err := os.Stat(backupPath(path, 1)); err != nil {
+		t.Errorf("no backup of the book before the merge: %v", err)
+	}
+}
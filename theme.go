@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme is the color palette every style is built from. Colors are hex
+// values or ANSI color numbers; an empty color leaves the terminal's own.
+type Theme struct {
+	Primary    string // Titles, borders and prompts
+	Accent     string // Search matches, errors and the detail border
+	Success    string // Status messages
+	Muted      string // Paths, hints and dividers
+	Bright     string // The selected project
+	Background string // Header backgrounds
+	Highlight  string // Tags and form labels
+	Warning    string // Missing and duplicate badges
+	Text       string // Everything else
+	Surface    string // Behind the selection and the search box
+	Inverse    string // Text on primary and highlight backgrounds
+}
+
+// colors maps the names used in the config to the fields of t.
+func (t *Theme) colors() map[string]*string {
+	return map[string]*string{
+		"primary":    &t.Primary,
+		"accent":     &t.Accent,
+		"success":    &t.Success,
+		"muted":      &t.Muted,
+		"bright":     &t.Bright,
+		"background": &t.Background,
+		"highlight":  &t.Highlight,
+		"warning":    &t.Warning,
+		"text":       &t.Text,
+		"surface":    &t.Surface,
+		"inverse":    &t.Inverse,
+	}
+}
+
+var darkTheme = Theme{
+	Primary:    "#A78BFA", // Lighter purple
+	Accent:     "#F472B6", // Pink
+	Success:    "#34D399", // Green
+	Muted:      "#9CA3AF", // Gray
+	Bright:     "#FBBF24", // Amber
+	Background: "#111827", // Darker bg
+	Highlight:  "#60A5FA", // Blue
+	Warning:    "#FB923C", // Orange
+	Text:       "#F3F4F6", // Light text
+	Surface:    "#1F2937",
+	Inverse:    "#FFFFFF",
+}
+
+var lightTheme = Theme{
+	Primary:    "#6D28D9", // Deep purple
+	Accent:     "#DB2777", // Dark pink
+	Success:    "#047857", // Dark green
+	Muted:      "#6B7280", // Gray
+	Bright:     "#B45309", // Dark amber
+	Background: "#F3F4F6", // Light gray bg
+	Highlight:  "#1D4ED8", // Dark blue
+	Warning:    "#C2410C", // Dark orange
+	Text:       "#111827", // Near black
+	Surface:    "#E5E7EB",
+	Inverse:    "#FFFFFF",
+}
+
+// highContrastTheme sticks to the basic ANSI colors at full intensity, which
+// every terminal renders legibly, on a black background.
+var highContrastTheme = Theme{
+	Primary:    "14", // Bright cyan
+	Accent:     "13", // Bright magenta
+	Success:    "10", // Bright green
+	Muted:      "7",  // White; nothing is dimmed
+	Bright:     "11", // Bright yellow
+	Background: "0",
+	Highlight:  "12", // Bright blue
+	Warning:    "11",
+	Text:       "15",
+	Surface:    "4", // Blue
+	Inverse:    "0",
+}
+
+// builtinThemes can be chosen by name with `name` in the [theme] table and
+// used as the base of user themes.
+var builtinThemes = map[string]Theme{
+	"dark":          darkTheme,
+	"light":         lightTheme,
+	"high-contrast": highContrastTheme,
+	"monochrome":    {},
+}
+
+// autoTheme picks dark or light from the terminal background.
+const autoTheme = "auto"
+
+var hexColor = regexp.MustCompile(`^#([0-9A-Fa-f]{3}|[0-9A-Fa-f]{6})$`)
+
+// validColor accepts what lipgloss.Color understands: hex colors and ANSI
+// color numbers.
+func validColor(s string) bool {
+	if hexColor.MatchString(s) {
+		return true
+	}
+	n, err := strconv.Atoi(s)
+	return err == nil && n >= 0 && n <= 255
+}
+
+// validateThemes checks [theme] and [themes], dropping what can't be used.
+func validateThemes(c *Config) []string {
+	var problems []string
+	report := func(format string, a ...any) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+	}
+	fields := new(Theme).colors()
+	known := strings.Join(sortedKeys(fields), ", ")
+
+	// checkColors drops unknown names and malformed colors from a table.
+	checkColors := func(table string, colors map[string]string, skip string) {
+		for _, name := range sortedKeys(colors) {
+			switch _, ok := fields[name]; {
+			case name == skip:
+			case !ok:
+				report("%s: unknown color %q (known: %s)", table, name, known)
+				delete(colors, name)
+			case !validColor(colors[name]):
+				report("%s.%s: %q is not a color (use #RRGGBB or 0–255)", table, name, colors[name])
+				delete(colors, name)
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(c.Themes) {
+		table := "themes." + name
+		if _, builtin := builtinThemes[name]; builtin || name == autoTheme {
+			report("%s: %s is a built-in theme; pick another name", table, name)
+			delete(c.Themes, name)
+			continue
+		}
+		if base := c.Themes[name]["base"]; base != "" {
+			if _, ok := builtinThemes[base]; !ok {
+				report("%s.base: %q is not a built-in theme (%s)", table, base, strings.Join(sortedKeys(builtinThemes), ", "))
+				delete(c.Themes[name], "base")
+			}
+		}
+		checkColors(table, c.Themes[name], "base")
+	}
+
+	if name := c.Theme["name"]; name != "" && name != autoTheme {
+		_, builtin := builtinThemes[name]
+		_, user := c.Themes[name]
+		if !builtin && !user {
+			names := append(sortedKeys(builtinThemes), sortedKeys(c.Themes)...)
+			report("theme.name: unknown theme %q (%s or auto)", name, strings.Join(names, ", "))
+			delete(c.Theme, "name")
+		}
+	}
+	checkColors("theme", c.Theme, "name")
+	return problems
+}
+
+// resolveTheme builds the theme cfg asks for. dark reports the terminal
+// background and only matters for auto, the default.
+func resolveTheme(cfg Config, dark func() bool) Theme {
+	if os.Getenv("NO_COLOR") != "" {
+		// https://no-color.org: no colors at all, whatever the config says.
+		return builtinThemes["monochrome"]
+	}
+
+	pickAuto := func() Theme {
+		if dark() {
+			return darkTheme
+		}
+		return lightTheme
+	}
+	var t Theme
+	name := cfg.Theme["name"]
+	if custom, ok := cfg.Themes[name]; ok {
+		t = pickAuto()
+		if base, ok := builtinThemes[custom["base"]]; ok {
+			t = base
+		}
+		t.override(custom)
+	} else if builtin, ok := builtinThemes[name]; ok {
+		t = builtin
+	} else {
+		t = pickAuto()
+	}
+	t.override(cfg.Theme)
+	return t
+}
+
+// override replaces the colors named in colors; other keys are ignored.
+func (t *Theme) override(colors map[string]string) {
+	fields := t.colors()
+	for name, value := range colors {
+		if field, ok := fields[name]; ok {
+			*field = value
+		}
+	}
+}
+
+// setupTheme applies the configured theme before the UI starts. out is where
+// the UI is drawn; its background decides the auto theme.
+func setupTheme(out *os.File) {
+	applyTheme(resolveTheme(userConfig, func() bool {
+		return lipgloss.NewRenderer(out).HasDarkBackground()
+	}))
+}
+
+// themeColor turns a theme color into a lipgloss color; "" is no color.
+func themeColor(s string) lipgloss.TerminalColor {
+	if s == "" {
+		return lipgloss.NoColor{}
+	}
+	return lipgloss.Color(s)
+}
+
+// applyTheme sets the palette from t and rebuilds the styles.
+func applyTheme(t Theme) {
+	primaryColor = themeColor(t.Primary)
+	accentColor = themeColor(t.Accent)
+	successColor = themeColor(t.Success)
+	mutedColor = themeColor(t.Muted)
+	brightColor = themeColor(t.Bright)
+	bgColor = themeColor(t.Background)
+	highlightColor = themeColor(t.Highlight)
+	warningColor = themeColor(t.Warning)
+	textColor = themeColor(t.Text)
+	surfaceColor = themeColor(t.Surface)
+	inverseColor = themeColor(t.Inverse)
+	buildStyles()
+
+	if t.Surface == "" {
+		// Without a background color the selection needs another cue.
+		selectedItemStyle = selectedItemStyle.Reverse(true)
+	}
+}
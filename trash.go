@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// archived reports whether p is in the trash.
+func (p Project) archived() bool {
+	return p.DeletedAt != nil
+}
+
+// activeCount returns the number of projects not in the trash.
+func (m *model) activeCount() int {
+	n := 0
+	for _, p := range m.projects {
+		if !p.archived() {
+			n++
+		}
+	}
+	return n
+}
+
+// trashProject moves the project at idx to the trash, unpinning it.
+func (m *model) trashProject(idx int) {
+	now := time.Now()
+	if m.projects[idx].Pinned > 0 {
+		var kept []int
+		for _, i := range m.pinOrder() {
+			if i != idx {
+				kept = append(kept, i)
+			}
+		}
+		m.projects[idx].Pinned = 0
+		m.renumberPins(kept)
+	}
+	m.projects[idx].DeletedAt = &now
+}
+
+// restoreProject takes the project at idx out of the trash.
+func (m *model) restoreProject(idx int) error {
+	if idx < 0 || idx >= len(m.projects) {
+		return fmt.Errorf("invalid index")
+	}
+	m.projects[idx].DeletedAt = nil
+	return m.saveProjects()
+}
+
+// purgeProject removes the project at idx from the store for good.
+func (m *model) purgeProject(idx int) error {
+	if idx < 0 || idx >= len(m.projects) {
+		return fmt.Errorf("invalid index")
+	}
+	if err := m.guardShared(idx); err != nil {
+		return err
+	}
+	m.projects = append(m.projects[:idx], m.projects[idx+1:]...)
+	return m.saveProjects()
+}
+
+// emptyTrash purges every archived project.
+func (m *model) emptyTrash() (int, error) {
+	kept := m.projects[:0:0]
+	for _, p := range m.projects {
+		if !p.archived() {
+			kept = append(kept, p)
+		}
+	}
+	n := len(m.projects) - len(kept)
+	m.projects = kept
+	return n, m.saveProjects()
+}
+
+// refreshTrash rebuilds the trash list, most recently deleted first.
+func (m *model) refreshTrash() {
+	m.trashIdxs = m.trashIdxs[:0]
+	for i, p := range m.projects {
+		if p.archived() {
+			m.trashIdxs = append(m.trashIdxs, i)
+		}
+	}
+	sort.SliceStable(m.trashIdxs, func(i, j int) bool {
+		return m.projects[m.trashIdxs[i]].DeletedAt.After(*m.projects[m.trashIdxs[j]].DeletedAt)
+	})
+	if m.trashCursor >= len(m.trashIdxs) {
+		m.trashCursor = max(len(m.trashIdxs)-1, 0)
+	}
+}
+
+// trashKeyAction runs fn on the project under the trash cursor, looking it up
+// by key so that reloads between prompt and answer can't hit the wrong entry.
+func trashKeyAction(key string, fn func(m *model, idx int) (string, error)) func(m *model) (string, error) {
+	return func(m *model) (string, error) {
+		for i, p := range m.projects {
+			if projectKey(p) == key {
+				status, err := fn(m, i)
+				m.refreshTrash()
+				return status, err
+			}
+		}
+		return "", fmt.Errorf("project no longer exists")
+	}
+}
+
+// updateTrash handles keys in the trash view.
+func (m model) updateTrash(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	tk := keymap.Trash
+	switch {
+	case msg.String() == quitKey:
+		return m, tea.Quit
+	case key.Matches(msg, tk.Close) || reopens(msg, keymap.List.Trash, "trash_view"):
+		m.mode = viewList
+		m.statusMessage = ""
+		m.applyFilter(m.textInput.Value())
+		return m, nil
+	case key.Matches(msg, tk.Empty):
+		if len(m.trashIdxs) == 0 {
+			return m, nil
+		}
+		m.confirm = &confirmPrompt{
+			question: fmt.Sprintf("Permanently delete all %d projects in the trash?", len(m.trashIdxs)),
+			onYes: func(m *model) (string, error) {
+				m.checkpoint("empty trash")
+				n, err := m.emptyTrash()
+				m.refreshTrash()
+				if err != nil {
+					m.dropCheckpoint()
+					return "", err
+				}
+				return fmt.Sprintf("✓ Purged %d projects", n), nil
+			},
+		}
+		return m, nil
+	}
+	if len(m.trashIdxs) == 0 {
+		return m, nil
+	}
+
+	p := m.projects[m.trashIdxs[m.trashCursor]]
+	switch {
+	case key.Matches(msg, tk.Down):
+		m.trashCursor = (m.trashCursor + 1) % len(m.trashIdxs)
+	case key.Matches(msg, tk.Up):
+		m.trashCursor = (m.trashCursor - 1 + len(m.trashIdxs)) % len(m.trashIdxs)
+	case key.Matches(msg, tk.Restore):
+		m.checkpoint(fmt.Sprintf("restore '%s'", p.Name))
+		status, err := trashKeyAction(projectKey(p), func(m *model, idx int) (string, error) {
+			return fmt.Sprintf("✓ Restored '%s'", p.Name), m.restoreProject(idx)
+		})(&m)
+		if err != nil {
+			m.dropCheckpoint()
+			m.statusMessage = fmt.Sprintf("Error: %v", err)
+			m.isError = true
+		} else {
+			m.statusMessage = status
+			m.isError = false
+		}
+	case key.Matches(msg, tk.Purge):
+		m.confirm = &confirmPrompt{
+			question: fmt.Sprintf("Permanently delete '%s'?", p.Name),
+			onYes: trashKeyAction(projectKey(p), func(m *model, idx int) (string, error) {
+				m.checkpoint(fmt.Sprintf("purge '%s'", p.Name))
+				if err := m.purgeProject(idx); err != nil {
+					m.dropCheckpoint()
+					return "", err
+				}
+				return fmt.Sprintf("✓ Purged '%s'", p.Name), nil
+			}),
+		}
+	}
+	return m, nil
+}
+
+// trashView renders the trash.
+func (m model) trashView() string {
+	var b strings.Builder
+
+	header := lipgloss.NewStyle().
+		Foreground(primaryColor).
+		Bold(true).
+		Background(bgColor).
+		Padding(1, 2).
+		MarginBottom(1).
+		Width(70).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(primaryColor).
+		Render(fmt.Sprintf("🗑 Trash (%d)", len(m.trashIdxs)))
+	b.WriteString(header + "\n")
+
+	muted := lipgloss.NewStyle().Foreground(mutedColor)
+	if len(m.trashIdxs) == 0 {
+		b.WriteString(muted.Italic(true).Render("The trash is empty") + "\n")
+	} else {
+		maxDisplay := max((m.viewport.Height-6)/3, 1)
+		start := max(min(m.trashCursor-maxDisplay/2, len(m.trashIdxs)-maxDisplay), 0)
+		end := min(start+maxDisplay, len(m.trashIdxs))
+
+		for i := start; i < end; i++ {
+			p := m.projects[m.trashIdxs[i]]
+			line := p.Name
+			if len(p.Tags) > 0 {
+				line += " " + renderTags(p.Tags, "")
+			}
+			if i == m.trashCursor {
+				b.WriteString(selectedItemStyle.Render("▶ "+line) + "\n")
+			} else {
+				b.WriteString(normalItemStyle.Render(line) + "\n")
+			}
+			b.WriteString(pathStyle.Render("   "+truncate(p.Path, 60)) + "\n")
+			b.WriteString(muted.Render("   deleted "+humanizeAge(time.Since(*p.DeletedAt))) + "\n")
+		}
+	}
+
+	b.WriteString(m.helpBar(keymap.Trash.shortHelp(), ""))
+
+	switch {
+	case m.confirm != nil:
+		b.WriteString("\n" + errorStyle.Render("? "+m.confirm.question) + helpStyle.Render("y/N"))
+	case m.statusMessage != "" && m.isError:
+		b.WriteString("\n" + errorStyle.Render("✗ "+m.statusMessage))
+	case m.statusMessage != "":
+		b.WriteString("\n" + statusStyle.Render(m.statusMessage))
+	}
+
+	return lipgloss.NewStyle().Padding(1, 2).Render(b.String())
+}
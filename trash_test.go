@@ -0,0 +1,103 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// listed returns the names applyFilter shows for query.
+func listed(m *model, query string) []string {
+	m.applyFilter(query)
+	var names []string
+	for _, i := range m.filteredIdxs {
+		names = append(names, m.projects[i].Name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestTrash(t *testing.T) {
+	tests := []struct {
+		name        string
+		change      func(t *testing.T, m *model) error
+		wantListed  []string
+		wantTrashed []string
+	}{
+		{"delete moves to the trash", func(t *testing.T, m *model) error {
+			return m.deleteProject(indexOfName(t, m, "api"))
+		}, []string{"cli"}, []string{"api", "blog"}},
+		{"restore", func(t *testing.T, m *model) error {
+			return m.restoreProject(indexOfName(t, m, "blog"))
+		}, []string{"api", "blog", "cli"}, nil},
+		{"purge", func(t *testing.T, m *model) error {
+			return m.purgeProject(indexOfName(t, m, "blog"))
+		}, []string{"api", "cli"}, nil},
+		{"empty the trash", func(t *testing.T, m *model) error {
+			if err := m.deleteProject(indexOfName(t, m, "cli")); err != nil {
+				return err
+			}
+			n, err := m.emptyTrash()
+			if n != 2 {
+				t.Errorf("emptyTrash() purged %d, want 2", n)
+			}
+			return err
+		}, []string{"api"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := storeWith(t, testProject("api", t0), testProject("blog", t1), testProject("cli", t2))
+			if err := m.deleteProject(indexOfName(t, &m, "blog")); err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.change(t, &m); err != nil {
+				t.Fatal(err)
+			}
+
+			// Reload to check what was saved.
+			if err := m.loadProjects(); err != nil {
+				t.Fatal(err)
+			}
+			if got := listed(&m, ""); !slices.Equal(got, tt.wantListed) {
+				t.Errorf("listed %v, want %v", got, tt.wantListed)
+			}
+			if got := listed(&m, "is:archived"); !slices.Equal(got, tt.wantTrashed) {
+				t.Errorf("trashed %v, want %v", got, tt.wantTrashed)
+			}
+			if m.activeCount() != len(tt.wantListed) {
+				t.Errorf("activeCount() = %d, want %d", m.activeCount(), len(tt.wantListed))
+			}
+		})
+	}
+}
+
+func TestTrashUnpins(t *testing.T) {
+	api, blog := testProject("api", t0), testProject("blog", t1)
+	api.Pinned, blog.Pinned = 1, 2
+	m := storeWith(t, api, blog)
+	if err := m.deleteProject(indexOfName(t, &m, "api")); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.projects[indexOfName(t, &m, "blog")].Pinned; got != 1 {
+		t.Errorf("blog pinned at %d after api was trashed, want 1", got)
+	}
+	if got := m.projects[indexOfName(t, &m, "api")].Pinned; got != 0 {
+		t.Errorf("trashed api pinned at %d, want 0", got)
+	}
+}
+
+func TestRefreshTrashOrder(t *testing.T) {
+	m := storeWith(t, testProject("api", t0), testProject("blog", t1), testProject("cli", t2))
+	for _, name := range []string{"blog", "api"} {
+		if err := m.deleteProject(indexOfName(t, &m, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.refreshTrash()
+	var names []string
+	for _, i := range m.trashIdxs {
+		names = append(names, m.projects[i].Name)
+	}
+	if want := []string{"api", "blog"}; !slices.Equal(names, want) {
+		t.Errorf("trash lists %v, want the last deleted first: %v", names, want)
+	}
+}
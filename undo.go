@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// maxUndo bounds the undo history kept for a session.
+const maxUndo = 50
+
+// undoEntry records the projects a change touched, by key, as they were
+// before it. A nil project didn't exist. Only those projects are put back,
+// so changes other processes made in the meantime survive an undo.
+type undoEntry struct {
+	label    string
+	projects map[string]*Project
+}
+
+// pendingUndo is the state taken by checkpoint, turned into an undoEntry by
+// the save that follows it.
+type pendingUndo struct {
+	label    string
+	snapshot []Project
+}
+
+// checkpoint notes the current projects before a change described by label,
+// e.g. "delete 'api'". The change is recorded for undo when it is saved.
+func (m *model) checkpoint(label string) {
+	m.pendingUndo = &pendingUndo{label: label, snapshot: cloneProjects(m.projects)}
+}
+
+// dropCheckpoint forgets the pending checkpoint of a change that failed, so
+// the next save doesn't record it.
+func (m *model) dropCheckpoint() {
+	m.pendingUndo = nil
+}
+
+// takeUndo turns a pending checkpoint into an undo entry holding the
+// projects changed since. It reports false when there is nothing to undo.
+func (m *model) takeUndo() (undoEntry, bool) {
+	pending := m.pendingUndo
+	m.pendingUndo = nil
+	if pending == nil {
+		return undoEntry{}, false
+	}
+	before := projectsByKey(pending.snapshot)
+	after := projectsByKey(m.projects)
+	e := undoEntry{label: pending.label, projects: make(map[string]*Project)}
+	for key, p := range before {
+		if a, ok := after[key]; !ok || !sameExceptOpens(*p, *a) {
+			e.projects[key] = p
+		}
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			e.projects[key] = nil
+		}
+	}
+	return e, len(e.projects) > 0
+}
+
+// recordUndo pushes e onto the undo history. Any redo history is dropped.
+func (m *model) recordUndo(e undoEntry) {
+	m.undoStack = append(m.undoStack, e)
+	if len(m.undoStack) > maxUndo {
+		m.undoStack = m.undoStack[len(m.undoStack)-maxUndo:]
+	}
+	m.redoStack = nil
+}
+
+// projectsByKey indexes the projects of this phonebook; shared projects
+// can't be changed, so they are left out.
+func projectsByKey(ps []Project) map[string]*Project {
+	byKey := make(map[string]*Project, len(ps))
+	for i := range ps {
+		if ps[i].Shared == "" {
+			byKey[projectKey(ps[i])] = &ps[i]
+		}
+	}
+	return byKey
+}
+
+// sameExceptOpens compares projects ignoring open history, which isn't part
+// of undo.
+func sameExceptOpens(a, b Project) bool {
+	a.OpenCount, a.Opens = 0, nil
+	b.OpenCount, b.Opens = 0, nil
+	return sameProject(a, b)
+}
+
+// undo puts back the projects changed by the last change.
+func (m *model) undo() (string, error) {
+	if len(m.undoStack) == 0 {
+		return "", fmt.Errorf("nothing to undo")
+	}
+	e := m.undoStack[len(m.undoStack)-1]
+	m.undoStack = m.undoStack[:len(m.undoStack)-1]
+	inverse, err := m.restore(e)
+	m.redoStack = append(m.redoStack, inverse)
+	if err != nil {
+		return "", err
+	}
+	return "↶ Undid " + e.label, nil
+}
+
+// redo reapplies the last undone change.
+func (m *model) redo() (string, error) {
+	if len(m.redoStack) == 0 {
+		return "", fmt.Errorf("nothing to redo")
+	}
+	e := m.redoStack[len(m.redoStack)-1]
+	m.redoStack = m.redoStack[:len(m.redoStack)-1]
+	inverse, err := m.restore(e)
+	m.undoStack = append(m.undoStack, inverse)
+	if err != nil {
+		return "", err
+	}
+	return "↷ Redid " + e.label, nil
+}
+
+// restore puts the projects in e back as they were and saves, returning the
+// entry that reverses it. Open history isn't part of undo, so projects keep
+// the opens recorded since.
+func (m *model) restore(e undoEntry) (undoEntry, error) {
+	inverse := undoEntry{label: e.label, projects: make(map[string]*Project, len(e.projects))}
+	index := make(map[string]int, len(m.projects))
+	for i, p := range m.projects {
+		index[projectKey(p)] = i
+	}
+
+	removed := make(map[int]bool)
+	keys := make([]string, 0, len(e.projects))
+	for key := range e.projects {
+		keys = append(keys, key)
+	}
+	// Re-added projects go back in a stable order.
+	sort.Strings(keys)
+	for _, key := range keys {
+		want := e.projects[key]
+		i, exists := index[key]
+		inverse.projects[key] = nil
+		if exists {
+			current := m.projects[i]
+			inverse.projects[key] = &current
+		}
+		switch {
+		case want == nil && exists:
+			removed[i] = true
+		case want == nil:
+		case exists:
+			p := *want
+			p.OpenCount, p.Opens = m.projects[i].OpenCount, m.projects[i].Opens
+			m.projects[i] = p
+		default:
+			m.projects = append(m.projects, *want)
+		}
+	}
+	if len(removed) > 0 {
+		kept := m.projects[:0]
+		for i, p := range m.projects {
+			if !removed[i] {
+				kept = append(kept, p)
+			}
+		}
+		m.projects = kept
+	}
+	// Pins restored next to ones made elsewhere may leave gaps or ties.
+	m.renumberPins(m.pinOrder())
+	return inverse, m.saveProjects()
+}
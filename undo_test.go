@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// snapshot describes the projects regardless of their order.
+func snapshot(ps []Project) string {
+	ps = cloneProjects(ps)
+	sort.Slice(ps, func(i, j int) bool { return projectKey(ps[i]) < projectKey(ps[j]) })
+	data, _ := json.Marshal(ps)
+	return string(data)
+}
+
+func TestUndoRedo(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, m *model) error
+	}{
+		{"add", func(t *testing.T, m *model) error {
+			return m.addProject(Project{Name: "cli", Path: "/src/cli"})
+		}},
+		{"edit", func(t *testing.T, m *model) error {
+			i := indexOfName(t, m, "api")
+			p := m.projects[i]
+			p.Name, p.Description = "api-v2", "edited"
+			return m.updateProject(i, p)
+		}},
+		{"delete", func(t *testing.T, m *model) error {
+			return m.deleteProject(indexOfName(t, m, "blog"))
+		}},
+		{"pin", func(t *testing.T, m *model) error {
+			return m.togglePin(indexOfName(t, m, "blog"))
+		}},
+		{"unpin", func(t *testing.T, m *model) error {
+			return m.togglePin(indexOfName(t, m, "pinned"))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pinned := testProject("pinned", t2)
+			pinned.Pinned = 1
+			m := storeWith(t, testProject("api", t0), testProject("blog", t1), pinned)
+			before := snapshot(m.projects)
+
+			m.checkpoint(tt.name)
+			if err := tt.change(t, &m); err != nil {
+				t.Fatal(err)
+			}
+			after := snapshot(m.projects)
+			if after == before {
+				t.Fatal("the change changed nothing")
+			}
+
+			status, err := m.undo()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := "↶ Undid " + tt.name; status != want {
+				t.Errorf("undo() = %q, want %q", status, want)
+			}
+			if got := snapshot(m.projects); got != before {
+				t.Errorf("after undo\n got %s\nwant %s", got, before)
+			}
+			if _, err := m.redo(); err != nil {
+				t.Fatal(err)
+			}
+			if got := snapshot(m.projects); got != after {
+				t.Errorf("after redo\n got %s\nwant %s", got, after)
+			}
+
+			// What undo and redo did was saved.
+			saved := model{projectsFile: m.projectsFile}
+			if err := saved.loadProjects(); err != nil {
+				t.Fatal(err)
+			}
+			if got := snapshot(saved.projects); got != after {
+				t.Errorf("saved\n got %s\nwant %s", got, after)
+			}
+		})
+	}
+}
+
+func TestUndoKeepsOpens(t *testing.T) {
+	m := storeWith(t, testProject("api", t0))
+	i := indexOfName(t, &m, "api")
+	m.checkpoint("edit")
+	p := m.projects[i]
+	p.Description = "edited"
+	if err := m.updateProject(i, p); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.markOpened(i); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.undo(); err != nil {
+		t.Fatal(err)
+	}
+	p = m.projects[indexOfName(t, &m, "api")]
+	if p.Description != "" || p.OpenCount != 1 {
+		t.Errorf("after undo: description %q, %d opens; want the edit undone and the open kept", p.Description, p.OpenCount)
+	}
+}
+
+func TestUndoWithoutHistory(t *testing.T) {
+	m := storeWith(t, testProject("api", t0))
+	if _, err := m.undo(); err == nil {
+		t.Error("undo() with nothing to undo succeeded")
+	}
+	if _, err := m.redo(); err == nil {
+		t.Error("redo() with nothing to redo succeeded")
+	}
+}
+
+func TestUndoHistoryIsBounded(t *testing.T) {
+	m := storeWith(t, testProject("api", t0))
+	for i := 0; i < maxUndo+10; i++ {
+		m.checkpoint(fmt.Sprintf("pin %d", i))
+		if err := m.togglePin(0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(m.undoStack) != maxUndo {
+		t.Errorf("%d undo entries, want %d", len(m.undoStack), maxUndo)
+	}
+	// A new change drops what was undone.
+	if _, err := m.undo(); err != nil {
+		t.Fatal(err)
+	}
+	m.checkpoint("pin again")
+	if err := m.togglePin(0); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.redoStack) != 0 {
+		t.Errorf("%d redo entries after a new change, want none", len(m.redoStack))
+	}
+}
+
+func TestUndoKeepsOtherChanges(t *testing.T) {
+	a, b := twoStores(t, testProject("api", t0), testProject("blog", t1))
+
+	b.checkpoint("delete")
+	if err := b.deleteProject(indexOfName(t, &b, "blog")); err != nil {
+		t.Fatal(err)
+	}
+	// Another process edits api and adds a project before the undo.
+	api := indexOfName(t, &a, "api")
+	p := a.projects[api]
+	p.Description = "edited elsewhere"
+	if err := a.updateProject(api, p); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.addProject(testProject("cli", t2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.saveProjects(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := b.undo(); err != nil {
+		t.Fatal(err)
+	}
+	saved := model{projectsFile: b.projectsFile}
+	if err := saved.loadProjects(); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, p := range saved.projects {
+		state := p.Description
+		if p.archived() {
+			state = "trashed"
+		}
+		got[p.Name] = state
+	}
+	want := map[string]string{"api": "edited elsewhere", "blog": "", "cli": ""}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("after undo: %v, want %v", got, want)
+	}
+}
+
+func TestFailedChangeLeavesNoUndo(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, m *model) error
+	}{
+		{"refused", func(t *testing.T, m *model) error {
+			_, err := m.relocateProject(projectKey(m.projects[indexOfName(t, m, "api")]), "/src/elsewhere")
+			return err
+		}},
+		{"unsaved", func(t *testing.T, m *model) error {
+			m.storeErr = errors.New("unreadable")
+			defer func() { m.storeErr = nil }()
+			m.checkpoint("delete 'blog'")
+			return m.deleteProject(indexOfName(t, m, "blog"))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := writeLayer(t, t.TempDir(), Project{Name: "api", Path: "/src/api"})
+			m := layeredStore(t, Config{Layers: []LayerConfig{{File: file}}}, testProject("blog", t0))
+
+			if err := tt.change(t, &m); err == nil {
+				t.Fatal("the change succeeded")
+			}
+			if m.pendingUndo != nil {
+				t.Error("the failed change left its checkpoint behind")
+			}
+			// A later save without a checkpoint of its own records nothing.
+			if err := m.addProject(Project{Name: "cli", Path: filepath.Join(t.TempDir(), "cli")}); err != nil {
+				t.Fatal(err)
+			}
+			if len(m.undoStack) != 0 {
+				t.Errorf("undo history %v, want none", m.undoStack)
+			}
+		})
+	}
+}